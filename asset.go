@@ -0,0 +1,109 @@
+package parcello
+
+import (
+	"encoding/json"
+	"html/template"
+	"path"
+	"strings"
+)
+
+// ManifestName is the name of the bundle entry that contains the manifest of
+// the fingerprinted resources. The entry is reserved, it is not added to the
+// resources of the manager.
+const ManifestName = "parcello.manifest.json"
+
+// AssetPath returns the fingerprinted path of given resource in the default
+// resource manager
+func AssetPath(name string) (string, error) {
+	return Manager.AssetPath(name)
+}
+
+// FuncMap returns the template functions that resolve the fingerprinted
+// paths of the resources in given file system:
+//
+//	<script src="{{ asset "js/app.js" }}"></script>
+func FuncMap(fileSystem FileSystemManager) template.FuncMap {
+	return template.FuncMap{
+		"asset": fileSystem.AssetPath,
+	}
+}
+
+// manifest maps the absolute paths of the resources to their fingerprinted
+// paths
+type manifest map[string]string
+
+func decodeManifest(data []byte) (manifest, error) {
+	entries := map[string]string{}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	items := manifest{}
+
+	for name, value := range entries {
+		items["/"+name] = "/" + value
+	}
+
+	return items, nil
+}
+
+func encodeManifest(items manifest) ([]byte, error) {
+	entries := map[string]string{}
+
+	for name, value := range items {
+		entries[strings.TrimPrefix(name, "/")] = strings.TrimPrefix(value, "/")
+	}
+
+	return json.MarshalIndent(entries, "", "  ")
+}
+
+// lookup returns the fingerprinted path of the named resource
+func (m manifest) lookup(name string) (string, bool) {
+	value, ok := m[path.Clean("/"+name)]
+	if !ok {
+		return "", false
+	}
+
+	if !strings.HasPrefix(name, "/") {
+		value = strings.TrimPrefix(value, "/")
+	}
+
+	return value, true
+}
+
+// merge adds the items of the manifest of another bundle. The resources that
+// have not been merged (see ConflictError) and the existing items are skipped.
+func (m manifest) merge(items manifest, conflict *ConflictError) manifest {
+	if len(items) == 0 {
+		return m
+	}
+
+	if m == nil {
+		m = manifest{}
+	}
+
+	for name, value := range items {
+		if _, ok := m[name]; ok || conflict.contains(strings.TrimPrefix(name, "/")) {
+			continue
+		}
+
+		m[name] = value
+	}
+
+	return m
+}
+
+// sub returns the manifest of the resources in given directory
+func (m manifest) sub(dir string) manifest {
+	dir = strings.TrimSuffix(path.Clean("/"+dir), "/") + "/"
+	items := manifest{}
+
+	for name, value := range m {
+		if strings.HasPrefix(name, dir) && strings.HasPrefix(value, dir) {
+			items["/"+strings.TrimPrefix(name, dir)] = "/" + strings.TrimPrefix(value, dir)
+		}
+	}
+
+	return items
+}
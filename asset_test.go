@@ -0,0 +1,200 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("AssetPath", func() {
+	var (
+		fileSystem parcello.FileSystem
+		compressor *parcello.ZipCompressor
+		hash       string
+	)
+
+	BeforeEach(func() {
+		hash = fmt.Sprintf("%x", sha256.Sum256([]byte("console.log('hello');")))[:8]
+
+		fileSystem = parcello.FromFS(fstest.MapFS{
+			"public/js/app.js": &fstest.MapFile{Data: []byte("console.log('hello');")},
+		})
+
+		compressor = &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+				Manifest: true,
+			},
+		}
+	})
+
+	manager := func() *parcello.ResourceManager {
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: fileSystem,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		manager := &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+		return manager
+	}
+
+	It("adds the manifest to the bundle", func() {
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: fileSystem,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+		Expect(err).NotTo(HaveOccurred())
+
+		file, err := reader.Open(parcello.ManifestName)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		data, err := ioutil.ReadAll(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(fmt.Sprintf(`{"public/js/app.js": "public/js/app.%s.js"}`, hash)))
+	})
+
+	It("does not add the manifest to the resources", func() {
+		manager := manager()
+
+		_, err := manager.ReadFile(parcello.ManifestName)
+		Expect(err).To(HaveOccurred())
+
+		files, err := manager.ReadDir("/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Name()).To(Equal("public"))
+	})
+
+	Context("when several bundles have a manifest", func() {
+		It("merges the manifests", func() {
+			manager := manager()
+
+			css := fmt.Sprintf("%x", sha256.Sum256([]byte("body {}")))[:8]
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.FromFS(fstest.MapFS{
+					"public/css/app.css": &fstest.MapFile{Data: []byte("body {}")},
+				}),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			name, err := manager.AssetPath("/public/js/app.js")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("/public/js/app." + hash + ".js"))
+
+			name, err = manager.AssetPath("/public/css/app.css")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("/public/css/app." + css + ".css"))
+		})
+	})
+
+	It("returns the fingerprinted path", func() {
+		name, err := manager().AssetPath("/public/js/app.js")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("/public/js/app." + hash + ".js"))
+
+		name, err = manager().AssetPath("public/js/app.js")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("public/js/app." + hash + ".js"))
+	})
+
+	Context("when the manager is a sub-directory", func() {
+		It("returns the fingerprinted path relative to the directory", func() {
+			dir, err := manager().Dir("/public")
+			Expect(err).NotTo(HaveOccurred())
+
+			name, err := dir.AssetPath("js/app.js")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("js/app." + hash + ".js"))
+		})
+	})
+
+	Context("when the bundle does not have a manifest", func() {
+		It("computes the fingerprinted path", func() {
+			compressor.Config.Manifest = false
+
+			manager := manager()
+
+			_, err := manager.ReadFile(parcello.ManifestName)
+			Expect(err).To(HaveOccurred())
+
+			name, err := manager.AssetPath("/public/js/app.js")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("/public/js/app." + hash + ".js"))
+		})
+	})
+
+	Context("when the resource does not exist", func() {
+		It("returns an error", func() {
+			_, err := manager().AssetPath("/public/js/main.js")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when a resource conflicts with the manifest", func() {
+		It("returns an error", func() {
+			compressor.Config.Recurive = false
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.FromFS(fstest.MapFS{
+					parcello.ManifestName: &fstest.MapFile{Data: []byte("{}")},
+				}),
+			})
+
+			Expect(err).To(MatchError("Resource 'parcello.manifest.json' conflicts with the manifest"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Context("when the file system is a directory", func() {
+		It("computes the fingerprinted path", func() {
+			hash := fmt.Sprintf("%x", sha256.Sum256([]byte("Report 2018\n")))[:8]
+
+			name, err := parcello.Dir("./fixture").AssetPath("resource/reports/2018.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("resource/reports/2018." + hash + ".txt"))
+		})
+	})
+
+	Describe("FuncMap", func() {
+		It("resolves the fingerprinted paths in templates", func() {
+			dir, err := manager().Dir("/public")
+			Expect(err).NotTo(HaveOccurred())
+
+			tmpl, err := template.New("index").
+				Funcs(parcello.FuncMap(dir)).
+				Parse(`<script src="/{{ asset "js/app.js" }}"></script>`)
+			Expect(err).NotTo(HaveOccurred())
+
+			buffer := &bytes.Buffer{}
+			Expect(tmpl.Execute(buffer, nil)).To(Succeed())
+			Expect(buffer.String()).To(Equal(`<script src="/js/app.` + hash + `.js"></script>`))
+		})
+
+		Context("when the resource does not exist", func() {
+			It("returns an error", func() {
+				tmpl, err := template.New("index").
+					Funcs(parcello.FuncMap(manager())).
+					Parse(`{{ asset "main.css" }}`)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(tmpl.Execute(&bytes.Buffer{}, nil)).NotTo(Succeed())
+			})
+		})
+	})
+})
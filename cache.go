@@ -0,0 +1,92 @@
+package parcello
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ContentCache keeps the decompressed content of the most recently used
+// nodes. The total size of the cached content never exceeds the limit.
+type ContentCache struct {
+	mutex   sync.Mutex
+	limit   int64
+	size    int64
+	entries *list.List
+	items   map[*Node]*list.Element
+}
+
+type cacheEntry struct {
+	node    *Node
+	content []byte
+}
+
+// NewContentCache creates a new cache that holds up to limit bytes
+func NewContentCache(limit int64) *ContentCache {
+	return &ContentCache{
+		limit:   limit,
+		entries: list.New(),
+		items:   make(map[*Node]*list.Element),
+	}
+}
+
+// Get returns the cached content of given node
+func (c *ContentCache) Get(node *Node) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	item, ok := c.items[node]
+	if !ok {
+		return nil, false
+	}
+
+	c.entries.MoveToFront(item)
+	return item.Value.(*cacheEntry).content, true
+}
+
+// Put caches the content of given node. The least recently used entries are
+// evicted if the cache is full. Content larger than the limit is not cached.
+func (c *ContentCache) Put(node *Node, content []byte) {
+	size := int64(len(content))
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if size > c.limit {
+		return
+	}
+
+	if item, ok := c.items[node]; ok {
+		c.remove(item)
+	}
+
+	for c.size+size > c.limit {
+		c.remove(c.entries.Back())
+	}
+
+	c.items[node] = c.entries.PushFront(&cacheEntry{node: node, content: content})
+	c.size = c.size + size
+}
+
+// Remove removes the content of given node from the cache
+func (c *ContentCache) Remove(node *Node) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if item, ok := c.items[node]; ok {
+		c.remove(item)
+	}
+}
+
+// Size returns the total size of the cached content
+func (c *ContentCache) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.size
+}
+
+func (c *ContentCache) remove(item *list.Element) {
+	entry := c.entries.Remove(item).(*cacheEntry)
+	delete(c.items, entry.node)
+	c.size = c.size - int64(len(entry.content))
+}
@@ -0,0 +1,83 @@
+package parcello_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("ContentCache", func() {
+	var (
+		cache *parcello.ContentCache
+		nodes []*parcello.Node
+	)
+
+	BeforeEach(func() {
+		cache = parcello.NewContentCache(10)
+		nodes = []*parcello.Node{
+			{Name: "a.txt"},
+			{Name: "b.txt"},
+			{Name: "c.txt"},
+		}
+	})
+
+	It("returns the cached content", func() {
+		cache.Put(nodes[0], []byte("hello"))
+
+		data, ok := cache.Get(nodes[0])
+		Expect(ok).To(BeTrue())
+		Expect(string(data)).To(Equal("hello"))
+		Expect(cache.Size()).To(Equal(int64(5)))
+	})
+
+	Context("when the content is not cached", func() {
+		It("returns false", func() {
+			data, ok := cache.Get(nodes[0])
+			Expect(ok).To(BeFalse())
+			Expect(data).To(BeNil())
+		})
+	})
+
+	Context("when the cache is full", func() {
+		It("evicts the least recently used content", func() {
+			cache.Put(nodes[0], []byte("hello"))
+			cache.Put(nodes[1], []byte("world"))
+
+			_, ok := cache.Get(nodes[0])
+			Expect(ok).To(BeTrue())
+
+			cache.Put(nodes[2], []byte("!"))
+
+			_, ok = cache.Get(nodes[1])
+			Expect(ok).To(BeFalse())
+
+			_, ok = cache.Get(nodes[0])
+			Expect(ok).To(BeTrue())
+
+			_, ok = cache.Get(nodes[2])
+			Expect(ok).To(BeTrue())
+			Expect(cache.Size()).To(Equal(int64(6)))
+		})
+	})
+
+	Context("when the content is larger than the limit", func() {
+		It("does not cache it", func() {
+			cache.Put(nodes[0], []byte("hello world"))
+
+			_, ok := cache.Get(nodes[0])
+			Expect(ok).To(BeFalse())
+			Expect(cache.Size()).To(BeZero())
+		})
+	})
+
+	Context("when the content is removed", func() {
+		It("does not return it", func() {
+			cache.Put(nodes[0], []byte("hello"))
+			cache.Remove(nodes[0])
+
+			_, ok := cache.Get(nodes[0])
+			Expect(ok).To(BeFalse())
+			Expect(cache.Size()).To(BeZero())
+		})
+	})
+})
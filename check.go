@@ -0,0 +1,382 @@
+package parcello
+
+import (
+	"archive/zip"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// embedUnescaper removes the escaping of the go:embed patterns
+var embedUnescaper = regexp.MustCompile(`\\(.)`)
+
+// Entry describes the content of a resource
+type Entry struct {
+	// Size of the resource in bytes
+	Size int64
+	// Hash is the hex encoded SHA-256 hash of the content
+	Hash string
+}
+
+// Difference describes a resource that differs between the source directory
+// and the bundle
+type Difference struct {
+	// Name is the path of the resource
+	Name string
+	// Source describes the resource in the source directory (nil if it does
+	// not exist)
+	Source *Entry
+	// Bundle describes the bundled resource (nil if it does not exist)
+	Bundle *Entry
+}
+
+// String returns the difference in a readable format. The resources that
+// are missing in the bundle are prefixed by '+', the ones that are missing
+// in the source directory by '-' and the changed ones by '~'.
+func (d *Difference) String() string {
+	switch {
+	case d.Bundle == nil:
+		return fmt.Sprintf("+ %s (size %d, sha256 %s)", d.Name, d.Source.Size, shortHash(d.Source.Hash))
+	case d.Source == nil:
+		return fmt.Sprintf("- %s (size %d, sha256 %s)", d.Name, d.Bundle.Size, shortHash(d.Bundle.Hash))
+	default:
+		return fmt.Sprintf("~ %s (size %d -> %d, sha256 %s -> %s)", d.Name,
+			d.Bundle.Size, d.Source.Size, shortHash(d.Bundle.Hash), shortHash(d.Source.Hash))
+	}
+}
+
+// Check builds the bundle of the source file system with given configuration
+// and compares it entry-by-entry (name, size and hash) with the bundled
+// resources. It returns the differences sorted by name.
+func Check(cfg *CompressorConfig, source FileSystem, bundle FileSystem) ([]*Difference, error) {
+	config := *cfg
+	config.Logger = ioutil.Discard
+	config.PrivateKey = nil
+
+	compressor := &ZipCompressor{Config: &config}
+
+	result, err := compressor.Compress(&CompressorContext{FileSystem: source})
+	if err != nil {
+		return nil, err
+	}
+
+	expected := &ResourceManager{NewReader: zip.NewReader}
+
+	if result != nil {
+		if err := expected.Add(BinaryResource(result.Body)); err != nil {
+			return nil, err
+		}
+	}
+
+	return diff(expected, bundle)
+}
+
+// CheckEmbed collects the resources of the source file system that should be
+// embedded with given configuration and compares them with the files listed
+// by the go:embed directives (see ParseEmbed). The content is not compared,
+// because it is embedded by the Go compiler from the source file system.
+func CheckEmbed(cfg *CompressorConfig, source FileSystem, files []string) ([]*Difference, error) {
+	config := *cfg
+	config.Logger = ioutil.Discard
+
+	compressor := &EmbedCompressor{Config: &config}
+
+	result, err := compressor.Compress(&CompressorContext{FileSystem: source})
+	if err != nil {
+		return nil, err
+	}
+
+	expected := map[string]bool{}
+
+	if result != nil {
+		for _, name := range result.Files {
+			expected[strings.TrimPrefix(filepath.ToSlash(name), "/")] = true
+		}
+	}
+
+	actual := map[string]bool{}
+
+	for _, name := range files {
+		actual[name] = true
+	}
+
+	differences := []*Difference{}
+
+	for name := range expected {
+		if !actual[name] {
+			entry, err := sourceEntry(source, name)
+			if err != nil {
+				return nil, err
+			}
+
+			differences = append(differences, &Difference{Name: name, Source: entry})
+		}
+	}
+
+	for name := range actual {
+		if !expected[name] {
+			entry, err := sourceEntry(source, name)
+			if os.IsNotExist(err) {
+				entry, err = &Entry{}, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			differences = append(differences, &Difference{Name: name, Bundle: entry})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Name < differences[j].Name
+	})
+
+	return differences, nil
+}
+
+// ParseEmbed parses the source code generated by the Generator with EmbedFS
+// enabled and returns the paths of the embedded files relative to given
+// resource directory (relative to the package directory)
+func ParseEmbed(source []byte, resourceDir string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", source, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.ToSlash(filepath.Clean(resourceDir))
+	files := []string{}
+
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !strings.HasPrefix(comment.Text, "//go:embed ") {
+				continue
+			}
+
+			patterns, err := embedPatterns(strings.TrimPrefix(comment.Text, "//go:embed "))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, pattern := range patterns {
+				name := embedUnescaper.ReplaceAllString(pattern, "$1")
+
+				if root != "." {
+					if !strings.HasPrefix(name, root+"/") {
+						return nil, fmt.Errorf("The embedded file '%s' is not inside the resource directory '%s'", name, root)
+					}
+
+					name = strings.TrimPrefix(name, root+"/")
+				}
+
+				files = append(files, name)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// embedPatterns splits the arguments of a go:embed directive
+func embedPatterns(args string) ([]string, error) {
+	patterns := []string{}
+
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		if args[0] != '"' && args[0] != '`' {
+			index := strings.IndexAny(args, " \t")
+			if index < 0 {
+				index = len(args)
+			}
+
+			patterns = append(patterns, args[:index])
+			args = args[index:]
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(args)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid go:embed pattern %s", args)
+		}
+
+		pattern, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, err
+		}
+
+		patterns = append(patterns, pattern)
+		args = args[len(quoted):]
+	}
+
+	return patterns, nil
+}
+
+func sourceEntry(source FileSystem, name string) (*Entry, error) {
+	file, err := source.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := fileHash(name, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{Size: info.Size(), Hash: checksum}, nil
+}
+
+// ParseResource parses the source code generated by the Generator and
+// returns the embedded resource
+func ParseResource(source []byte) (*Resource, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", source, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data  []byte
+		found bool
+	)
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || found || len(call.Args) == 0 {
+			return !found
+		}
+
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (selector.Sel.Name != "AddResource" && selector.Sel.Name != "AddNamespaceResource") {
+			return true
+		}
+
+		literal, ok := call.Args[len(call.Args)-1].(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+
+		if data, err = bytesOf(literal); err == nil {
+			found = true
+		}
+
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("The source code does not contain an embedded resource")
+	}
+
+	return BinaryResource(data), nil
+}
+
+func bytesOf(literal *ast.CompositeLit) ([]byte, error) {
+	data := make([]byte, 0, len(literal.Elts))
+
+	for _, element := range literal.Elts {
+		value, ok := element.(*ast.BasicLit)
+		if !ok || value.Kind != token.INT {
+			return nil, fmt.Errorf("Invalid byte literal")
+		}
+
+		item, err := strconv.ParseUint(value.Value, 0, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, byte(item))
+	}
+
+	return data, nil
+}
+
+// diff compares the resources of both file systems
+func diff(source, bundle FileSystem) ([]*Difference, error) {
+	expected, err := entries(source)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, err := entries(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	differences := []*Difference{}
+
+	for name, entry := range expected {
+		other, ok := actual[name]
+
+		switch {
+		case !ok:
+			differences = append(differences, &Difference{Name: name, Source: entry})
+		case *entry != *other:
+			differences = append(differences, &Difference{Name: name, Source: entry, Bundle: other})
+		}
+	}
+
+	for name, entry := range actual {
+		if _, ok := expected[name]; !ok {
+			differences = append(differences, &Difference{Name: name, Bundle: entry})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Name < differences[j].Name
+	})
+
+	return differences, nil
+}
+
+// entries returns the entries of all files in the file system
+func entries(fileSystem FileSystem) (map[string]*Entry, error) {
+	items := map[string]*Entry{}
+
+	err := fileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		checksum, err := contentHash(fileSystem, path)
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(filepath.ToSlash(path), "/")
+		items[name] = &Entry{Size: info.Size(), Hash: checksum}
+
+		return nil
+	})
+
+	return items, err
+}
+
+func shortHash(value string) string {
+	const length = 12
+
+	if len(value) > length {
+		return value[:length]
+	}
+
+	return value
+}
@@ -0,0 +1,286 @@
+package parcello_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/parcello/fake"
+)
+
+var _ = Describe("Check", func() {
+	var (
+		cfg    *parcello.CompressorConfig
+		source fstest.MapFS
+		bundle *parcello.ResourceManager
+	)
+
+	BeforeEach(func() {
+		cfg = &parcello.CompressorConfig{
+			Recurive: true,
+		}
+
+		source = fstest.MapFS{
+			"index.html":  &fstest.MapFile{Data: []byte("<html></html>")},
+			"css/app.css": &fstest.MapFile{Data: []byte("body {}")},
+			"main.go":     &fstest.MapFile{Data: []byte("package main")},
+		}
+	})
+
+	JustBeforeEach(func() {
+		compressor := &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+
+		result, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.FromFS(fstest.MapFS{
+				"index.html":  &fstest.MapFile{Data: []byte("<html></html>")},
+				"css/app.css": &fstest.MapFile{Data: []byte("body {}")},
+			}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		bundle = &parcello.ResourceManager{}
+		Expect(bundle.Add(parcello.BinaryResource(result.Body))).To(Succeed())
+	})
+
+	It("does not return any differences", func() {
+		differences, err := parcello.Check(cfg, parcello.FromFS(source), bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(differences).To(BeEmpty())
+	})
+
+	Context("when the resources differ", func() {
+		BeforeEach(func() {
+			source["index.html"] = &fstest.MapFile{Data: []byte("<html>changed</html>")}
+			source["js/app.js"] = &fstest.MapFile{Data: []byte("app")}
+			delete(source, "css/app.css")
+		})
+
+		It("returns the differences", func() {
+			differences, err := parcello.Check(cfg, parcello.FromFS(source), bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(differences).To(HaveLen(3))
+
+			Expect(differences[0].Name).To(Equal("css/app.css"))
+			Expect(differences[0].Source).To(BeNil())
+			Expect(differences[0].Bundle.Size).To(Equal(int64(7)))
+
+			Expect(differences[1].Name).To(Equal("index.html"))
+			Expect(differences[1].Source.Size).To(Equal(int64(20)))
+			Expect(differences[1].Bundle.Size).To(Equal(int64(13)))
+			Expect(differences[1].Source.Hash).NotTo(Equal(differences[1].Bundle.Hash))
+
+			Expect(differences[2].Name).To(Equal("js/app.js"))
+			Expect(differences[2].Bundle).To(BeNil())
+
+			Expect(differences[0].String()).To(HavePrefix("- css/app.css (size 7, sha256 "))
+			Expect(differences[1].String()).To(HavePrefix("~ index.html (size 13 -> 20, sha256 "))
+			Expect(differences[2].String()).To(HavePrefix("+ js/app.js (size 3, sha256 "))
+		})
+	})
+
+	Context("when the content has the same size", func() {
+		BeforeEach(func() {
+			source["css/app.css"] = &fstest.MapFile{Data: []byte("body{ }")}
+		})
+
+		It("compares the hashes", func() {
+			differences, err := parcello.Check(cfg, parcello.FromFS(source), bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(differences).To(HaveLen(1))
+			Expect(differences[0].Name).To(Equal("css/app.css"))
+		})
+	})
+
+	Context("when the resources are ignored", func() {
+		BeforeEach(func() {
+			cfg.IgnorePatterns = []string{"*.css"}
+		})
+
+		It("does not compare them", func() {
+			differences, err := parcello.Check(cfg, parcello.FromFS(source), bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(differences).To(HaveLen(1))
+			Expect(differences[0].Name).To(Equal("css/app.css"))
+			Expect(differences[0].Source).To(BeNil())
+		})
+	})
+
+	Context("when the source cannot be walked", func() {
+		It("returns an error", func() {
+			fileSystem := &fake.FileSystem{}
+			fileSystem.WalkReturns(fmt.Errorf("oh no!"))
+
+			differences, err := parcello.Check(cfg, fileSystem, bundle)
+			Expect(differences).To(BeNil())
+			Expect(err).To(MatchError("oh no!"))
+		})
+	})
+})
+
+var _ = Describe("ParseResource", func() {
+	var source []byte
+
+	BeforeEach(func() {
+		buffer := &bytes.Buffer{}
+
+		file := &fake.File{}
+		file.WriteStub = buffer.Write
+
+		fileSystem := &fake.FileSystem{}
+		fileSystem.OpenFileReturns(file, nil)
+
+		generator := &parcello.Generator{
+			FileSystem: fileSystem,
+			Config: &parcello.GeneratorConfig{
+				Package:   "database",
+				Namespace: "github.com/phogolabs/database",
+			},
+		}
+
+		Expect(generator.Compose(&parcello.Bundle{Name: "resource", Body: []byte{80, 75, 5, 6, 255}})).To(Succeed())
+		source = buffer.Bytes()
+	})
+
+	It("returns the embedded resource", func() {
+		resource, err := parcello.ParseResource(source)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resource.Size).To(Equal(int64(5)))
+
+		data := make([]byte, 5)
+		_, err = resource.Body.ReadAt(data, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal([]byte{80, 75, 5, 6, 255}))
+	})
+
+	Context("when the source code does not contain a resource", func() {
+		It("returns an error", func() {
+			resource, err := parcello.ParseResource([]byte("package database\n"))
+			Expect(resource).To(BeNil())
+			Expect(err).To(MatchError("The source code does not contain an embedded resource"))
+		})
+	})
+
+	Context("when the source code is invalid", func() {
+		It("returns an error", func() {
+			resource, err := parcello.ParseResource([]byte("package"))
+			Expect(resource).To(BeNil())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("CheckEmbed", func() {
+	var (
+		cfg    *parcello.CompressorConfig
+		source fstest.MapFS
+	)
+
+	BeforeEach(func() {
+		cfg = &parcello.CompressorConfig{
+			Recurive: true,
+		}
+
+		source = fstest.MapFS{
+			"index.html":  &fstest.MapFile{Data: []byte("<html></html>")},
+			"css/app.css": &fstest.MapFile{Data: []byte("body {}")},
+			"main.go":     &fstest.MapFile{Data: []byte("package main")},
+		}
+	})
+
+	It("does not return any differences", func() {
+		differences, err := parcello.CheckEmbed(cfg, parcello.FromFS(source), []string{"css/app.css", "index.html"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(differences).To(BeEmpty())
+	})
+
+	Context("when the embedded files differ", func() {
+		It("returns the differences", func() {
+			differences, err := parcello.CheckEmbed(cfg, parcello.FromFS(source), []string{"index.html", "js/app.js", "main.go"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(differences).To(HaveLen(3))
+
+			Expect(differences[0].String()).To(HavePrefix("+ css/app.css (size 7, sha256 "))
+			Expect(differences[1].Name).To(Equal("js/app.js"))
+			Expect(differences[1].Source).To(BeNil())
+			Expect(differences[1].Bundle).To(Equal(&parcello.Entry{}))
+			Expect(differences[2].String()).To(HavePrefix("- main.go (size 12, sha256 "))
+		})
+	})
+
+	Context("when the resources are ignored", func() {
+		BeforeEach(func() {
+			cfg.IgnorePatterns = []string{"*.css"}
+		})
+
+		It("does not expect them", func() {
+			differences, err := parcello.CheckEmbed(cfg, parcello.FromFS(source), []string{"index.html"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(differences).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("ParseEmbed", func() {
+	var (
+		source    []byte
+		generator *parcello.Generator
+	)
+
+	BeforeEach(func() {
+		buffer := &bytes.Buffer{}
+
+		file := &fake.File{}
+		file.WriteStub = buffer.Write
+
+		fileSystem := &fake.FileSystem{}
+		fileSystem.OpenFileReturns(file, nil)
+
+		generator = &parcello.Generator{
+			FileSystem: fileSystem,
+			Config: &parcello.GeneratorConfig{
+				Package:     "database",
+				EmbedFS:     true,
+				ResourceDir: "public",
+			},
+		}
+
+		bundle := &parcello.Bundle{
+			Name:  "resource",
+			Files: []string{"index.html", "css/[draft] app.css"},
+		}
+
+		Expect(generator.Compose(bundle)).To(Succeed())
+		source = buffer.Bytes()
+	})
+
+	It("returns the embedded files", func() {
+		files, err := parcello.ParseEmbed(source, "public")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(ConsistOf("index.html", "css/[draft] app.css"))
+	})
+
+	Context("when the file is outside of the resource directory", func() {
+		It("returns an error", func() {
+			files, err := parcello.ParseEmbed(source, "assets")
+			Expect(files).To(BeNil())
+			Expect(err).To(MatchError("The embedded file 'public/index.html' is not inside the resource directory 'assets'"))
+		})
+	})
+
+	Context("when the source code is invalid", func() {
+		It("returns an error", func() {
+			files, err := parcello.ParseEmbed([]byte("package"), ".")
+			Expect(files).To(BeNil())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phogolabs/cli"
+	"github.com/phogolabs/parcello"
+	"gopkg.in/yaml.v2"
+)
+
+// configNames are the names of the configuration file in order of precedence
+var configNames = []string{"parcello.yaml", "parcello.yml"}
+
+// config is the content of the configuration file
+type config struct {
+	// Bundles are the bundles that are built by a single invocation
+	Bundles []*options `yaml:"bundles"`
+}
+
+// options controls how a bundle is built
+type options struct {
+	// ResourceDir is the path to the resource directory
+	ResourceDir string `yaml:"resource-dir"`
+	// BundlePath is the path to the bundle directory or binary
+	BundlePath string `yaml:"bundle-path"`
+	// ResourceType is the type of the bundle (bundle, source-code, embed)
+	ResourceType string `yaml:"resource-type"`
+	// Package is the name of the generated package (default: the name of
+	// the bundle directory)
+	Package string `yaml:"package"`
+	// Namespace is the namespace of the resources (default: the import path
+	// of the package)
+	Namespace string `yaml:"namespace"`
+	// Recursive embeds or bundles the resources recursively
+	Recursive bool `yaml:"recursive"`
+	// Ignore contains the patterns of the ignored files
+	Ignore []string `yaml:"ignore"`
+	// Include contains the patterns of the included files
+	Include []string `yaml:"include"`
+	// Mount contains the directories mounted in the bundle (src:dest)
+	Mount []string `yaml:"mount"`
+	// Symlinks is the symbolic link policy (follow, preserve, error)
+	Symlinks string `yaml:"symlinks"`
+	// Compression is the compression method
+	Compression string `yaml:"compression"`
+	// Precompress contains the patterns of the precompressed files
+	Precompress []string `yaml:"precompress"`
+	// Store contains the patterns of the files stored without compression
+	Store []string `yaml:"store"`
+	// ContentType contains the content types of the matching files
+	// (pattern=type)
+	ContentType []string `yaml:"content-type"`
+	// Manifest adds a manifest of the fingerprinted file names
+	Manifest bool `yaml:"manifest"`
+	// Reproducible produces byte-identical bundles for identical resources
+	Reproducible bool `yaml:"reproducible"`
+	// SignKey is the path to the private key that signs the resources
+	SignKey string `yaml:"sign-key"`
+	// IncludeDocs includes API documentation in generated source code
+	IncludeDocs bool `yaml:"include-docs"`
+	// Force embeds the resources even if they have not changed
+	Force bool `yaml:"-"`
+}
+
+// UnmarshalYAML unmarshals the options with the defaults of the command line
+// flags
+func (o *options) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain options
+
+	*o = options{
+		ResourceDir:  ".",
+		BundlePath:   ".",
+		ResourceType: "source-code",
+		Compression:  "deflate",
+		IncludeDocs:  true,
+	}
+
+	return unmarshal((*plain)(o))
+}
+
+// findConfig returns the path of the configuration file in the directory or
+// in any of its parents up to the module root (the directory of go.mod). It
+// returns an empty string if there is no such file.
+func findConfig(dir string) (string, error) {
+	for {
+		for _, name := range configNames {
+			path := filepath.Join(dir, name)
+
+			_, err := os.Stat(path)
+
+			switch {
+			case err == nil:
+				return path, nil
+			case !os.IsNotExist(err):
+				return "", err
+			}
+		}
+
+		_, err := os.Stat(filepath.Join(dir, "go.mod"))
+
+		switch {
+		case err == nil:
+			return "", nil
+		case !os.IsNotExist(err):
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// configPath returns the path of the configuration file of the command. The
+// file given by --config cannot be combined with the flags that configure a
+// bundle. Otherwise the file is discovered only if no such flags are given,
+// so the flags always take precedence over a discovered file.
+func configPath(ctx *cli.Context, defaults []cli.Flag) (string, error) {
+	names := explicitFlags(ctx, defaults)
+	path := ctx.String("config")
+
+	switch {
+	case path != "" && len(names) > 0:
+		return "", fmt.Errorf("The flags %s cannot be combined with the configuration file '%s'", strings.Join(names, ", "), path)
+	case path != "" || len(names) > 0:
+		return path, nil
+	}
+
+	resourceDir, err := filepath.Abs(ctx.String("resource-dir"))
+	if err != nil {
+		return "", err
+	}
+
+	return findConfig(resourceDir)
+}
+
+// explicitFlags returns the names of the flags that configure a bundle and
+// differ from their default values
+func explicitFlags(ctx *cli.Context, defaults []cli.Flag) []string {
+	values := map[string]string{}
+
+	for _, flag := range defaults {
+		values[flagName(flag)] = fmt.Sprint(flag.Get())
+	}
+
+	names := []string{}
+
+	for _, flag := range ctx.Command.Flags {
+		name := flagName(flag)
+
+		switch name {
+		case "config", "quiet", "force":
+			continue
+		}
+
+		if value, ok := values[name]; ok && value != fmt.Sprint(flag.Get()) {
+			names = append(names, "--"+name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// flagName returns the long name of the flag
+func flagName(flag cli.Flag) string {
+	accessor := &cli.FlagAccessor{Flag: flag}
+	return strings.TrimSpace(strings.Split(accessor.Name(), ",")[0])
+}
+
+// loadConfig reads the configuration file. The relative paths of the bundles
+// are resolved against the directory of the file.
+func loadConfig(path string) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if len(cfg.Bundles) == 0 {
+		return nil, fmt.Errorf("%s: no bundles are configured", path)
+	}
+
+	dir := filepath.Dir(path)
+
+	for _, bundle := range cfg.Bundles {
+		bundle.ResourceDir = resolve(dir, bundle.ResourceDir)
+		bundle.BundlePath = resolve(dir, bundle.BundlePath)
+
+		if bundle.SignKey != "" {
+			bundle.SignKey = resolve(dir, bundle.SignKey)
+		}
+
+		for index, mount := range bundle.Mount {
+			src, dest, err := parcello.ParseMount(mount)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+
+			bundle.Mount[index] = resolve(dir, src) + ":" + dest
+		}
+	}
+
+	return cfg, nil
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/cli"
+)
+
+var _ = Describe("Config", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "parcello")
+		Expect(err).NotTo(HaveOccurred())
+
+		dir, err = filepath.EvalSymlinks(dir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+
+		return path
+	}
+
+	Describe("findConfig", func() {
+		It("finds the configuration file in the directory", func() {
+			path := write("parcello.yaml", "")
+
+			Expect(findConfig(dir)).To(Equal(path))
+		})
+
+		It("finds the configuration file in the parent directories", func() {
+			path := write("parcello.yml", "")
+			write("web/public/index.html", "")
+
+			Expect(findConfig(filepath.Join(dir, "web", "public"))).To(Equal(path))
+		})
+
+		It("prefers parcello.yaml to parcello.yml", func() {
+			path := write("parcello.yaml", "")
+			write("parcello.yml", "")
+
+			Expect(findConfig(dir)).To(Equal(path))
+		})
+
+		Context("when the directory is in a module", func() {
+			It("does not search above the module root", func() {
+				write("parcello.yaml", "")
+				write("service/go.mod", "module github.com/phogolabs/service")
+				write("service/web/index.html", "")
+
+				Expect(findConfig(filepath.Join(dir, "service", "web"))).To(BeEmpty())
+			})
+
+			It("finds the configuration file in the module root", func() {
+				path := write("service/parcello.yaml", "")
+				write("service/go.mod", "module github.com/phogolabs/service")
+				write("service/web/index.html", "")
+
+				Expect(findConfig(filepath.Join(dir, "service", "web"))).To(Equal(path))
+			})
+		})
+	})
+
+	Describe("loadConfig", func() {
+		It("resolves the relative paths against the directory of the file", func() {
+			path := write("config/parcello.yaml", `
+bundles:
+  - resource-dir: web/dist
+    bundle-path: ../cmd/server
+    sign-key: keys/private.pem
+    mount:
+      - docs:public/docs
+      - /srv/assets:public/assets
+  - resource-dir: /srv/templates
+    resource-type: bundle
+    compression: zstd
+    include-docs: false
+`)
+
+			cfg, err := loadConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Bundles).To(HaveLen(2))
+
+			bundle := cfg.Bundles[0]
+			Expect(bundle.ResourceDir).To(Equal(filepath.Join(dir, "config", "web", "dist")))
+			Expect(bundle.BundlePath).To(Equal(filepath.Join(dir, "cmd", "server")))
+			Expect(bundle.SignKey).To(Equal(filepath.Join(dir, "config", "keys", "private.pem")))
+			Expect(bundle.Mount).To(Equal([]string{
+				filepath.Join(dir, "config", "docs") + ":public/docs",
+				"/srv/assets:public/assets",
+			}))
+			Expect(bundle.ResourceType).To(Equal("source-code"))
+			Expect(bundle.Compression).To(Equal("deflate"))
+			Expect(bundle.IncludeDocs).To(BeTrue())
+
+			bundle = cfg.Bundles[1]
+			Expect(bundle.ResourceDir).To(Equal("/srv/templates"))
+			Expect(bundle.BundlePath).To(Equal(filepath.Join(dir, "config")))
+			Expect(bundle.SignKey).To(BeEmpty())
+			Expect(bundle.ResourceType).To(Equal("bundle"))
+			Expect(bundle.Compression).To(Equal("zstd"))
+			Expect(bundle.IncludeDocs).To(BeFalse())
+		})
+
+		Context("when the file contains an unknown key", func() {
+			It("returns an error", func() {
+				path := write("parcello.yaml", "bundles:\n  - resource-dir: web\n    recursve: true\n")
+
+				cfg, err := loadConfig(path)
+				Expect(err).To(MatchError(HavePrefix(path + ": ")))
+				Expect(err.Error()).To(ContainSubstring("field recursve not found"))
+				Expect(cfg).To(BeNil())
+			})
+		})
+
+		Context("when the file does not configure bundles", func() {
+			It("returns an error", func() {
+				path := write("parcello.yaml", "bundles: []\n")
+
+				cfg, err := loadConfig(path)
+				Expect(err).To(MatchError(path + ": no bundles are configured"))
+				Expect(cfg).To(BeNil())
+			})
+		})
+
+		Context("when the mount is invalid", func() {
+			It("returns an error", func() {
+				path := write("parcello.yaml", "bundles:\n  - mount: [\":public\"]\n")
+
+				cfg, err := loadConfig(path)
+				Expect(err).To(MatchError(path + ": The source of mount ':public' is empty"))
+				Expect(cfg).To(BeNil())
+			})
+		})
+	})
+
+	Describe("configPath", func() {
+		var (
+			ctx  *cli.Context
+			path string
+		)
+
+		set := func(name, value string) {
+			for _, flag := range ctx.Command.Flags {
+				if flagName(flag) == name {
+					Expect(flag.Set(value)).To(Succeed())
+					return
+				}
+			}
+
+			Fail("unknown flag " + name)
+		}
+
+		BeforeEach(func() {
+			path = write("parcello.yaml", "bundles:\n  - resource-dir: web\n")
+			write("web/index.html", "")
+
+			ctx = &cli.Context{Command: &cli.Command{Flags: flags()}}
+			set("resource-dir", filepath.Join(dir, "web"))
+		})
+
+		It("discovers the configuration file", func() {
+			ctx.Command.Flags = flags()
+			set("quiet", "true")
+
+			current, err := os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Chdir(current)
+
+			Expect(os.Chdir(filepath.Join(dir, "web"))).To(Succeed())
+			Expect(configPath(ctx, flags())).To(Equal(path))
+		})
+
+		Context("when the bundle flags are given", func() {
+			It("does not discover the configuration file", func() {
+				set("recursive", "true")
+
+				Expect(configPath(ctx, flags())).To(BeEmpty())
+			})
+
+			It("cannot be combined with the configuration file", func() {
+				set("config", path)
+				set("bundle-path", "cmd")
+
+				_, err := configPath(ctx, flags())
+				Expect(err).To(MatchError("The flags --bundle-path, --resource-dir cannot be combined with the configuration file '" + path + "'"))
+			})
+		})
+
+		Context("when the configuration file is given", func() {
+			It("returns the path of the file", func() {
+				ctx.Command.Flags = flags()
+				set("config", "parcello.yml")
+
+				Expect(configPath(ctx, flags())).To(Equal("parcello.yml"))
+			})
+		})
+
+		Context("when the command is check", func() {
+			It("ignores the flags of the other commands", func() {
+				ctx.Command.Flags = checkFlags()
+				set("recursive", "true")
+
+				Expect(configPath(ctx, checkFlags())).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -2,10 +2,13 @@
 package main
 
 import (
+	"archive/zip"
+	"crypto/ed25519"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -20,6 +23,10 @@ var version string = "unknown"
 const (
 	// ErrCodeArg is returned when an invalid argument is passed to CLI
 	ErrCodeArg = 101
+	// ErrCodeVerify is returned when the bundle cannot be verified
+	ErrCodeVerify = 102
+	// ErrCodeCheck is returned when the bundle differs from the resources
+	ErrCodeCheck = 103
 )
 
 func main() {
@@ -32,40 +39,28 @@ func main() {
 		Writer:    os.Stdout,
 		ErrWriter: os.Stderr,
 		Action:    run,
-		Flags: []cli.Flag{
-			&cli.BoolFlag{
-				Name:  "quiet, q",
-				Usage: "disable logging",
+		Commands: []*cli.Command{
+			{
+				Name:      "check",
+				Usage:     "check whether the bundle is up to date with the resource directory",
+				UsageText: "parcello check [command options]",
+				Action:    check,
+				Flags:     checkFlags(),
 			},
-			&cli.BoolFlag{
-				Name:  "recursive, r",
-				Usage: "embed or bundle the resources recursively",
-			},
-			&cli.StringFlag{
-				Name:  "resource-dir, d",
-				Usage: "path to directory",
-				Value: ".",
-			},
-			&cli.StringFlag{
-				Name:  "bundle-path, b",
-				Usage: "path to the bundle directory or binary",
-				Value: ".",
-			},
-			&cli.StringFlag{
-				Name:  "resource-type, t",
-				Usage: "resource type. (supported: bundle, source-code)",
-				Value: "source-code",
-			},
-			&cli.StringSliceFlag{
-				Name:  "ignore, i",
-				Usage: "ignore file name",
-			},
-			&cli.BoolFlag{
-				Name:  "include-docs",
-				Usage: "include API documentation in generated source code",
-				Value: true,
+			{
+				Name:      "verify",
+				Usage:     "verify the signature of the resources bundled to a binary",
+				UsageText: "parcello verify [command options] <binary>",
+				Action:    verify,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "public-key, k",
+						Usage: "path to the PEM encoded Ed25519 public key",
+					},
+				},
 			},
 		},
+		Flags: flags(),
 	}
 
 	sort.Sort(cli.FlagsByName(app.Flags))
@@ -74,49 +69,338 @@ func main() {
 	app.Run(os.Args)
 }
 
+// flags returns the flags of the bundle command
+func flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "disable logging",
+		},
+		&cli.BoolFlag{
+			Name:  "recursive, r",
+			Usage: "embed or bundle the resources recursively",
+		},
+		&cli.StringFlag{
+			Name:  "resource-dir, d",
+			Usage: "path to directory",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "bundle-path, b",
+			Usage: "path to the bundle directory or binary",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "resource-type, t",
+			Usage: "resource type. (supported: bundle, source-code, embed)",
+			Value: "source-code",
+		},
+		&cli.StringSliceFlag{
+			Name:  "ignore, i",
+			Usage: "ignore the matching files (.gitignore syntax, for instance **/*.map or !*.go)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "include",
+			Usage: "include only the matching files (for instance web/dist/**)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "mount",
+			Usage: "mount a directory in the bundle in src:dest format (for instance web/dist:public)",
+		},
+		&cli.StringFlag{
+			Name:  "compression, c",
+			Usage: "compression method. (supported: deflate, store, zstd, brotli, xz)",
+			Value: "deflate",
+		},
+		&cli.StringSliceFlag{
+			Name:  "precompress, p",
+			Usage: "add precompressed (br, zstd, gzip) variants of the matching files (for instance *.js)",
+		},
+		&cli.StringFlag{
+			Name:  "symlinks",
+			Usage: "symbolic link policy. (supported: follow, preserve, error)",
+			Value: "follow",
+		},
+		&cli.StringSliceFlag{
+			Name:  "store, s",
+			Usage: "store the matching files without compression (for instance *.png)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "content-type",
+			Usage: "content type of the matching files in pattern=type format (for instance *.wasm=application/wasm)",
+		},
+		&cli.BoolFlag{
+			Name:  "manifest, m",
+			Usage: "add a manifest of the fingerprinted file names",
+		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "path to the configuration file (default: parcello.yaml in the resource directory or its parents up to go.mod)",
+		},
+		&cli.BoolFlag{
+			Name:  "force, f",
+			Usage: "embed the resources even if they have not changed",
+		},
+		&cli.BoolFlag{
+			Name:  "reproducible",
+			Usage: "produce byte-identical bundles for identical resources (honors SOURCE_DATE_EPOCH)",
+		},
+		&cli.StringFlag{
+			Name:  "namespace, n",
+			Usage: "namespace of the resources (default: the import path of the package)",
+		},
+		&cli.StringFlag{
+			Name:  "sign-key, k",
+			Usage: "path to the PEM encoded Ed25519 private key that signs the resources",
+		},
+		&cli.BoolFlag{
+			Name:  "include-docs",
+			Usage: "include API documentation in generated source code",
+			Value: true,
+		},
+	}
+}
+
+// checkFlags returns the flags of the check command
+func checkFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "recursive, r",
+			Usage: "check the resources recursively",
+		},
+		&cli.StringFlag{
+			Name:  "resource-dir, d",
+			Usage: "path to directory",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "bundle-path, b",
+			Usage: "path to the bundle directory or binary",
+			Value: ".",
+		},
+		&cli.StringFlag{
+			Name:  "resource-type, t",
+			Usage: "resource type. (supported: bundle, source-code)",
+			Value: "source-code",
+		},
+		&cli.StringSliceFlag{
+			Name:  "ignore, i",
+			Usage: "ignore file name",
+		},
+		&cli.StringSliceFlag{
+			Name:  "include",
+			Usage: "include only the matching files (for instance web/dist/**)",
+		},
+		&cli.StringFlag{
+			Name:  "symlinks",
+			Usage: "symbolic link policy. (supported: follow, preserve, error)",
+			Value: "follow",
+		},
+		&cli.StringSliceFlag{
+			Name:  "mount",
+			Usage: "mount a directory in the bundle in src:dest format (for instance web/dist:public)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "precompress, p",
+			Usage: "the precompressed (br, zstd, gzip) variants of the matching files are bundled",
+		},
+		&cli.BoolFlag{
+			Name:  "manifest, m",
+			Usage: "the manifest of the fingerprinted file names is bundled",
+		},
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "path to the configuration file (default: parcello.yaml in the resource directory or its parents up to go.mod)",
+		},
+	}
+}
+
 func run(ctx *cli.Context) error {
-	rType := ctx.String("resource-type")
+	path, err := configPath(ctx, flags())
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	if path == "" {
+		return build(ctx, flagOptions(ctx))
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	fmt.Fprintf(logger(ctx), "Building %d bundle(s) from '%s'\n", len(cfg.Bundles), path)
+
+	for _, opts := range cfg.Bundles {
+		opts.Force = ctx.Bool("force")
+
+		if err := build(ctx, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	switch strings.ToLower(rType) {
+func build(ctx *cli.Context, opts *options) error {
+	switch strings.ToLower(opts.ResourceType) {
 	case "source-code":
-		return embed(ctx)
+		return embed(ctx, opts)
 	case "bundle":
-		return bundle(ctx)
+		return bundle(ctx, opts)
+	case "embed":
+		return embedFS(ctx, opts)
 	default:
-		err := fmt.Errorf("Invalid resource type '%s'", rType)
+		err := fmt.Errorf("Invalid resource type '%s'", opts.ResourceType)
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+}
+
+func flagOptions(ctx *cli.Context) *options {
+	return &options{
+		ResourceDir:  ctx.String("resource-dir"),
+		BundlePath:   ctx.String("bundle-path"),
+		ResourceType: ctx.String("resource-type"),
+		Namespace:    ctx.String("namespace"),
+		Recursive:    ctx.Bool("recursive"),
+		Ignore:       ctx.StringSlice("ignore"),
+		Include:      ctx.StringSlice("include"),
+		Mount:        ctx.StringSlice("mount"),
+		Symlinks:     ctx.String("symlinks"),
+		Compression:  ctx.String("compression"),
+		Precompress:  ctx.StringSlice("precompress"),
+		Store:        ctx.StringSlice("store"),
+		ContentType:  ctx.StringSlice("content-type"),
+		Manifest:     ctx.Bool("manifest"),
+		Reproducible: ctx.Bool("reproducible"),
+		SignKey:      ctx.String("sign-key"),
+		IncludeDocs:  ctx.Bool("include-docs"),
+		Force:        ctx.Bool("force"),
+	}
+}
+
+func embed(ctx *cli.Context, opts *options) error {
+	resourceDir, err := filepath.Abs(opts.ResourceDir)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	bundlePath, err := filepath.Abs(opts.BundlePath)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	resources, err := resources(resourceDir, opts.Mount)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	key, err := privateKey(opts.SignKey)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	types, err := contentTypes(opts.ContentType)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	namespace, err := namespace(opts.Namespace, bundlePath)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	embedder := &parcello.Embedder{
+		Logger:     logger(ctx),
+		FileSystem: resources,
+		Force:      opts.Force,
+		Composer: &parcello.Generator{
+			FileSystem: parcello.Dir(bundlePath),
+			Config: &parcello.GeneratorConfig{
+				Package:     packageName(opts, bundlePath),
+				InlcudeDocs: opts.IncludeDocs,
+				Namespace:   namespace,
+			},
+		},
+		Compressor: &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:          logger(ctx),
+				Filename:        "resource",
+				IgnorePatterns:  opts.Ignore,
+				IncludePatterns: opts.Include,
+				Recurive:        opts.Recursive,
+				Symlinks:        parcello.SymlinkPolicy(opts.Symlinks),
+				Methods:         methods(opts.Store),
+				ContentTypes:    types,
+				Compression:     opts.Compression,
+				Precompression:  precompression(opts.Precompress),
+				Manifest:        opts.Manifest,
+				PrivateKey:      key,
+				Reproducible:    opts.Reproducible,
+			},
+		},
+	}
+
+	if err := embedder.Embed(); err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeArg)
 	}
+
+	return nil
 }
 
-func embed(ctx *cli.Context) error {
-	resourceDir, err := filepath.Abs(ctx.String("resource-dir"))
+func embedFS(ctx *cli.Context, opts *options) error {
+	resourceDir, err := filepath.Abs(opts.ResourceDir)
 	if err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeArg)
 	}
 
-	bundlePath, err := filepath.Abs(ctx.String("bundle-path"))
+	bundlePath, err := filepath.Abs(opts.BundlePath)
 	if err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeArg)
 	}
 
-	_, packageName := filepath.Split(bundlePath)
+	if len(opts.Mount) > 0 {
+		err = fmt.Errorf("The mounts are not supported by the embed resource type")
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	rel, err := filepath.Rel(bundlePath, resourceDir)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		err = fmt.Errorf("The resource directory '%s' is not inside the package directory '%s'", resourceDir, bundlePath)
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	namespace, err := namespace(opts.Namespace, bundlePath)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
 
 	embedder := &parcello.Embedder{
 		Logger:     logger(ctx),
 		FileSystem: parcello.Dir(resourceDir),
+		Force:      opts.Force,
 		Composer: &parcello.Generator{
 			FileSystem: parcello.Dir(bundlePath),
 			Config: &parcello.GeneratorConfig{
-				Package:     packageName,
-				InlcudeDocs: ctx.Bool("include-docs"),
+				Package:     packageName(opts, bundlePath),
+				InlcudeDocs: opts.IncludeDocs,
+				EmbedFS:     true,
+				ResourceDir: rel,
+				Namespace:   namespace,
 			},
 		},
-		Compressor: &parcello.ZipCompressor{
+		Compressor: &parcello.EmbedCompressor{
 			Config: &parcello.CompressorConfig{
-				Logger:         logger(ctx),
-				Filename:       "resource",
-				IgnorePatterns: ctx.StringSlice("ignore"),
-				Recurive:       ctx.Bool("recursive"),
+				Logger:          logger(ctx),
+				Filename:        "resource",
+				IgnorePatterns:  opts.Ignore,
+				IncludePatterns: opts.Include,
+				Recurive:        opts.Recursive,
 			},
 		},
 	}
@@ -128,26 +412,50 @@ func embed(ctx *cli.Context) error {
 	return nil
 }
 
-func bundle(ctx *cli.Context) error {
-	resourceDir, err := filepath.Abs(ctx.String("resource-dir"))
+func bundle(ctx *cli.Context, opts *options) error {
+	resourceDir, err := filepath.Abs(opts.ResourceDir)
 	if err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeArg)
 	}
 
-	bundlePath, err := filepath.Abs(ctx.String("bundle-path"))
+	bundlePath, err := filepath.Abs(opts.BundlePath)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	resources, err := resources(resourceDir, opts.Mount)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	key, err := privateKey(opts.SignKey)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	types, err := contentTypes(opts.ContentType)
 	if err != nil {
 		return cli.NewExitError(err.Error(), ErrCodeArg)
 	}
 
 	bundler := &parcello.Bundler{
 		Logger:     logger(ctx),
-		FileSystem: parcello.Dir(resourceDir),
+		FileSystem: resources,
 		Compressor: &parcello.ZipCompressor{
 			Config: &parcello.CompressorConfig{
-				Logger:         logger(ctx),
-				Filename:       "resource",
-				IgnorePatterns: ctx.StringSlice("ignore"),
-				Recurive:       ctx.Bool("recursive"),
+				Logger:          logger(ctx),
+				Filename:        "resource",
+				IgnorePatterns:  opts.Ignore,
+				IncludePatterns: opts.Include,
+				Recurive:        opts.Recursive,
+				Symlinks:        parcello.SymlinkPolicy(opts.Symlinks),
+				Methods:         methods(opts.Store),
+				ContentTypes:    types,
+				Compression:     opts.Compression,
+				Precompression:  precompression(opts.Precompress),
+				Manifest:        opts.Manifest,
+				PrivateKey:      key,
+				Reproducible:    opts.Reproducible,
 			},
 		},
 	}
@@ -166,6 +474,385 @@ func bundle(ctx *cli.Context) error {
 	return nil
 }
 
+func verify(ctx *cli.Context) error {
+	if len(ctx.Args) != 1 {
+		return cli.NewExitError("The path to the binary is required", ErrCodeArg)
+	}
+
+	if ctx.String("public-key") == "" {
+		return cli.NewExitError("The public key is required", ErrCodeArg)
+	}
+
+	data, err := ioutil.ReadFile(ctx.String("public-key"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	key, err := parcello.ParsePublicKey(data)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	path := ctx.Args[0]
+
+	file, err := os.Open(path)
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	resource := &parcello.Resource{
+		Body: file,
+		Size: info.Size(),
+	}
+
+	if err := parcello.VerifyResource(resource, key); err != nil {
+		err = fmt.Errorf("The bundle of '%s' cannot be verified: %v", path, err)
+		return cli.NewExitError(err.Error(), ErrCodeVerify)
+	}
+
+	fmt.Fprintf(ctx.Writer, "The bundle of '%s' is verified\n", path)
+	return nil
+}
+
+func check(ctx *cli.Context) error {
+	path, err := configPath(ctx, checkFlags())
+	if err != nil {
+		return cli.NewExitError(err.Error(), ErrCodeArg)
+	}
+
+	bundles := []*options{flagOptions(ctx)}
+
+	if path != "" {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeArg)
+		}
+
+		bundles = cfg.Bundles
+	}
+
+	outdated := []string{}
+
+	for _, opts := range bundles {
+		bundlePath, ok, err := checkBundle(ctx, opts)
+		if err != nil {
+			return cli.NewExitError(err.Error(), ErrCodeArg)
+		}
+
+		if !ok {
+			outdated = append(outdated, bundlePath)
+		}
+	}
+
+	if len(outdated) == 1 {
+		err = fmt.Errorf("The bundle of '%s' is out of date", outdated[0])
+		return cli.NewExitError(err.Error(), ErrCodeCheck)
+	}
+
+	if len(outdated) > 1 {
+		err = fmt.Errorf("The bundles of '%s' are out of date", strings.Join(outdated, "', '"))
+		return cli.NewExitError(err.Error(), ErrCodeCheck)
+	}
+
+	return nil
+}
+
+// checkBundle compares the bundle with the resources. It returns the path of
+// the bundle and whether the bundle is up to date.
+func checkBundle(ctx *cli.Context, opts *options) (string, bool, error) {
+	resourceDir, err := filepath.Abs(opts.ResourceDir)
+	if err != nil {
+		return "", false, err
+	}
+
+	bundlePath, err := filepath.Abs(opts.BundlePath)
+	if err != nil {
+		return "", false, err
+	}
+
+	resources, err := resources(resourceDir, opts.Mount)
+	if err != nil {
+		return "", false, err
+	}
+
+	var bundle parcello.FileSystemManager
+
+	switch rType := opts.ResourceType; strings.ToLower(rType) {
+	case "source-code":
+		bundlePath = filepath.Join(bundlePath, "resource.go")
+		bundle, err = sourceBundle(bundlePath)
+	case "bundle":
+		bundle, err = binaryBundle(bundlePath)
+	case "embed":
+		bundlePath = filepath.Join(bundlePath, "resource.go")
+		return checkEmbed(ctx, opts, resourceDir, bundlePath)
+	default:
+		err = fmt.Errorf("Invalid resource type '%s'", rType)
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	cfg := &parcello.CompressorConfig{
+		IgnorePatterns:  opts.Ignore,
+		IncludePatterns: opts.Include,
+		Recurive:        opts.Recursive,
+		Symlinks:        parcello.SymlinkPolicy(opts.Symlinks),
+		Precompression:  precompression(opts.Precompress),
+		Manifest:        opts.Manifest,
+	}
+
+	differences, err := parcello.Check(cfg, resources, bundle)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, difference := range differences {
+		fmt.Fprintln(ctx.Writer, difference)
+	}
+
+	if len(differences) > 0 {
+		return bundlePath, false, nil
+	}
+
+	fmt.Fprintf(ctx.Writer, "The bundle of '%s' is up to date\n", bundlePath)
+	return bundlePath, true, nil
+}
+
+// checkEmbed compares the files listed by the go:embed directives of the
+// generated source code with the resources that should be embedded. Their
+// content is embedded by the compiler, so it cannot be out of date.
+func checkEmbed(ctx *cli.Context, opts *options, resourceDir, bundlePath string) (string, bool, error) {
+	if len(opts.Mount) > 0 {
+		return "", false, fmt.Errorf("The mounts are not supported by the embed resource type")
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(bundlePath), resourceDir)
+	if err != nil {
+		return "", false, err
+	}
+
+	data, err := ioutil.ReadFile(bundlePath)
+	if err != nil {
+		return "", false, err
+	}
+
+	files, err := parcello.ParseEmbed(data, rel)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %v", bundlePath, err)
+	}
+
+	cfg := &parcello.CompressorConfig{
+		IgnorePatterns:  opts.Ignore,
+		IncludePatterns: opts.Include,
+		Recurive:        opts.Recursive,
+	}
+
+	differences, err := parcello.CheckEmbed(cfg, parcello.Dir(resourceDir), files)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, difference := range differences {
+		fmt.Fprintln(ctx.Writer, difference)
+	}
+
+	if len(differences) > 0 {
+		return bundlePath, false, nil
+	}
+
+	fmt.Fprintf(ctx.Writer, "The bundle of '%s' is up to date\n", bundlePath)
+	return bundlePath, true, nil
+}
+
+func sourceBundle(path string) (parcello.FileSystemManager, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := parcello.ParseResource(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	manager := &parcello.ResourceManager{}
+
+	if err := manager.Add(resource); err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+func binaryBundle(path string) (parcello.FileSystemManager, error) {
+	dir, name := filepath.Split(path)
+
+	manager, err := parcello.NewResourceManager(&parcello.ResourceManagerConfig{
+		Path:       name,
+		FileSystem: parcello.Dir(dir),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := manager.Err(); err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+func privateKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parcello.ParsePrivateKey(data)
+}
+
+// namespace returns the namespace of the resources. By default it is the
+// import path of the package, which is resolved from the closest go.mod file.
+// The resources are not namespaced if the package is not in a module.
+func namespace(name, packageDir string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+
+	for dir := packageDir; ; dir = filepath.Dir(dir) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+
+		switch {
+		case os.IsNotExist(err):
+			if parent := filepath.Dir(dir); parent == dir {
+				return "", nil
+			}
+
+			continue
+		case err != nil:
+			return "", err
+		}
+
+		module := modulePath(data)
+		if module == "" {
+			return "", fmt.Errorf("The module path is missing in '%s'", filepath.Join(dir, "go.mod"))
+		}
+
+		rel, err := filepath.Rel(dir, packageDir)
+		if err != nil {
+			return "", err
+		}
+
+		return path.Join(module, filepath.ToSlash(rel)), nil
+	}
+}
+
+// modulePath returns the module path of go.mod file
+func modulePath(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
+	}
+
+	return ""
+}
+
+// resources returns the file system of the resource directory. The mounted
+// directories are combined with it.
+func resources(resourceDir string, mounts []string) (parcello.FileSystem, error) {
+	if len(mounts) == 0 {
+		return parcello.Dir(resourceDir), nil
+	}
+
+	fileSystem := parcello.NewMountFileSystem(&parcello.Mount{
+		FileSystem: parcello.Dir(resourceDir),
+	})
+
+	for _, mount := range mounts {
+		src, dest, err := parcello.ParseMount(mount)
+		if err != nil {
+			return nil, err
+		}
+
+		if src, err = filepath.Abs(src); err != nil {
+			return nil, err
+		}
+
+		fileSystem.Mounts = append(fileSystem.Mounts, &parcello.Mount{
+			Path:       dest,
+			FileSystem: parcello.Dir(src),
+		})
+	}
+
+	return fileSystem, nil
+}
+
+// packageName returns the name of the generated package. By default it is the
+// name of the package directory.
+func packageName(opts *options, packageDir string) string {
+	if opts.Package != "" {
+		return opts.Package
+	}
+
+	_, name := filepath.Split(packageDir)
+	return name
+}
+
+func methods(patterns []string) []parcello.CompressionMethod {
+	rules := []parcello.CompressionMethod{}
+
+	for _, pattern := range patterns {
+		rules = append(rules, parcello.CompressionMethod{
+			Pattern: pattern,
+			Method:  zip.Store,
+		})
+	}
+
+	return rules
+}
+
+func contentTypes(values []string) ([]parcello.ContentType, error) {
+	rules := []parcello.ContentType{}
+
+	for _, value := range values {
+		rule, err := parcello.ParseContentType(value)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func precompression(patterns []string) *parcello.Precompression {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	return &parcello.Precompression{
+		Patterns: patterns,
+	}
+}
+
 func logger(ctx *cli.Context) io.Writer {
 	if ctx.GlobalBool("quiet") {
 		return ioutil.Discard
@@ -0,0 +1,240 @@
+package parcello
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	// Zstd is the zip method of Zstandard compression
+	Zstd uint16 = 93
+	// XZ is the zip method of XZ compression
+	XZ uint16 = 95
+	// Brotli is the zip method of Brotli compression. Note that the method is
+	// not assigned by the zip specification.
+	Brotli uint16 = 121
+)
+
+var codecs = &codecRegistry{
+	items: map[string]*Codec{},
+}
+
+func init() {
+	RegisterCodec(&Codec{
+		Name:   "store",
+		Method: zip.Store,
+	})
+
+	RegisterCodec(&Codec{
+		Name:   "deflate",
+		Method: zip.Deflate,
+	})
+
+	RegisterCodec(&Codec{
+		Name:         "zstd",
+		Method:       Zstd,
+		Compressor:   zstdCompressor,
+		Decompressor: zstdDecompressor,
+	})
+
+	RegisterCodec(&Codec{
+		Name:         "brotli",
+		Method:       Brotli,
+		Compressor:   brotliCompressor,
+		Decompressor: brotliDecompressor,
+	})
+
+	RegisterCodec(&Codec{
+		Name:         "xz",
+		Method:       XZ,
+		Compressor:   xzCompressor,
+		Decompressor: xzDecompressor,
+	})
+}
+
+// Codec represents a compression method that can be used in the bundles
+type Codec struct {
+	// Name of the codec
+	Name string
+	// Method is the zip compression method
+	Method uint16
+	// Compressor compresses the resources (nil for the methods supported by archive/zip)
+	Compressor zip.Compressor
+	// Decompressor decompresses the resources (nil for the methods supported by archive/zip)
+	Decompressor zip.Decompressor
+}
+
+// RegisterCodec registers a codec that is used by the ZipCompressor and
+// the ResourceManager. The codec replaces the codec with the same name.
+func RegisterCodec(codec *Codec) {
+	codecs.register(codec)
+}
+
+// LookupCodec returns the registered codec with given name
+func LookupCodec(name string) (*Codec, error) {
+	return codecs.lookup(name)
+}
+
+type codecRegistry struct {
+	mutex sync.RWMutex
+	items map[string]*Codec
+}
+
+func (r *codecRegistry) register(codec *Codec) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.items[strings.ToLower(codec.Name)] = codec
+}
+
+func (r *codecRegistry) lookup(name string) (*Codec, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if codec, ok := r.items[strings.ToLower(name)]; ok {
+		return codec, nil
+	}
+
+	names := []string{}
+	for key := range r.items {
+		names = append(names, key)
+	}
+
+	sort.Strings(names)
+	return nil, fmt.Errorf("Unsupported compression '%s' (supported: %s)", name, strings.Join(names, ", "))
+}
+
+func (r *codecRegistry) list() []*Codec {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	items := []*Codec{}
+	for _, codec := range r.items {
+		items = append(items, codec)
+	}
+
+	return items
+}
+
+// registerCompressors registers the compressors of all codecs in the writer
+func registerCompressors(writer *zip.Writer) {
+	for _, codec := range codecs.list() {
+		if codec.Compressor != nil {
+			writer.RegisterCompressor(codec.Method, codec.Compressor)
+		}
+	}
+}
+
+// registerDecompressors registers the decompressors of all codecs in the reader
+func registerDecompressors(reader *zip.Reader) {
+	for _, codec := range codecs.list() {
+		if codec.Decompressor != nil {
+			reader.RegisterDecompressor(codec.Method, codec.Decompressor)
+		}
+	}
+}
+
+func zstdCompressor(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+}
+
+func zstdDecompressor(r io.Reader) io.ReadCloser {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return &errReader{err: err}
+	}
+
+	return decoder.IOReadCloser()
+}
+
+func brotliCompressor(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriterLevel(w, brotli.BestCompression), nil
+}
+
+func brotliDecompressor(r io.Reader) io.ReadCloser {
+	return io.NopCloser(brotli.NewReader(r))
+}
+
+func xzCompressor(w io.Writer) (io.WriteCloser, error) {
+	// the xz writer writes the stream header when it is created, but zip
+	// creates the compressor before it writes the file header
+	writer := &lazyWriter{
+		writer: w,
+		create: func(w io.Writer) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		},
+	}
+
+	return writer, nil
+}
+
+func xzDecompressor(r io.Reader) io.ReadCloser {
+	reader, err := xz.NewReader(r)
+	if err != nil {
+		return &errReader{err: err}
+	}
+
+	return io.NopCloser(reader)
+}
+
+// lazyWriter creates the underlying writer on first write or close
+type lazyWriter struct {
+	writer io.Writer
+	create func(io.Writer) (io.WriteCloser, error)
+	target io.WriteCloser
+}
+
+// Write writes the data to the underlying writer
+func (w *lazyWriter) Write(p []byte) (int, error) {
+	if err := w.init(); err != nil {
+		return 0, err
+	}
+
+	return w.target.Write(p)
+}
+
+// Close closes the underlying writer
+func (w *lazyWriter) Close() error {
+	if err := w.init(); err != nil {
+		return err
+	}
+
+	return w.target.Close()
+}
+
+func (w *lazyWriter) init() error {
+	if w.target != nil {
+		return nil
+	}
+
+	target, err := w.create(w.writer)
+	if err != nil {
+		return err
+	}
+
+	w.target = target
+	return nil
+}
+
+// errReader is a reader that always fails
+type errReader struct {
+	err error
+}
+
+// Read returns the error
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// Close closes the reader
+func (r *errReader) Close() error {
+	return nil
+}
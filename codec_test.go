@@ -0,0 +1,114 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Codec", func() {
+	var compressor *parcello.ZipCompressor
+
+	BeforeEach(func() {
+		compressor = &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   ioutil.Discard,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+	})
+
+	DescribeTable("compresses and decompresses the resources",
+		func(name string, method uint16) {
+			compressor.Config.Compression = name
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reader.File[0].Method).To(Equal(method))
+
+			manager := &parcello.ResourceManager{}
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			file, err := manager.Open("/resource/reports/2018.txt")
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := ioutil.ReadAll(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("Report 2018\n"))
+		},
+		Entry("store", "store", zip.Store),
+		Entry("deflate", "deflate", zip.Deflate),
+		Entry("zstd", "zstd", parcello.Zstd),
+		Entry("brotli", "brotli", parcello.Brotli),
+		Entry("xz", "xz", parcello.XZ),
+	)
+
+	Context("when the codec is not registered", func() {
+		It("returns an error", func() {
+			codec, err := parcello.LookupCodec("lz4")
+			Expect(codec).To(BeNil())
+			Expect(err).To(MatchError(HavePrefix("Unsupported compression 'lz4' (supported: brotli, deflate")))
+		})
+
+		It("fails the compression", func() {
+			compressor.Config.Compression = "lz4"
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Context("when a custom codec is registered", func() {
+		BeforeEach(func() {
+			parcello.RegisterCodec(&parcello.Codec{
+				Name:   "identity",
+				Method: 2001,
+				Compressor: func(w io.Writer) (io.WriteCloser, error) {
+					return &nopWriteCloser{Writer: w}, nil
+				},
+				Decompressor: func(r io.Reader) io.ReadCloser {
+					return ioutil.NopCloser(r)
+				},
+			})
+		})
+
+		It("uses the codec", func() {
+			compressor.Config.Compression = "identity"
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			manager := &parcello.ResourceManager{}
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			data, err := manager.ReadFile("/resource/scripts/schema.sql")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).NotTo(BeEmpty())
+		})
+	})
+})
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (w *nopWriteCloser) Close() error {
+	return nil
+}
@@ -3,13 +3,24 @@ package parcello
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var _ Compressor = &ZipCompressor{}
+var (
+	_ Compressor = &ZipCompressor{}
+	_ Compressor = &EmbedCompressor{}
+)
 
 // ErrSkipResource skips a particular file from processing
 var ErrSkipResource = fmt.Errorf("Skip Resource Error")
@@ -20,10 +31,65 @@ type CompressorConfig struct {
 	Logger io.Writer
 	// Filename is the name of the compressed bundle
 	Filename string
-	// IgnorePatterns provides a list of all files that has to be ignored
+	// IgnorePatterns provides a list of all files that has to be ignored. The
+	// patterns follow the .gitignore semantics: "**" matches any number of
+	// directories and "!" re-includes the matching files (for instance
+	// "!*.go"). The IgnoreFileName files are applied as well.
 	IgnorePatterns []string
+	// IncludePatterns restricts the bundle to the files that match at least
+	// one of the patterns (for instance "web/dist/**"). All files are
+	// included if it is empty.
+	IncludePatterns []string
 	// Recurive enables embedding the resources recursively
 	Recurive bool
+	// Methods provides the compression methods of the files that match given
+	// patterns (the first match wins)
+	Methods []CompressionMethod
+	// Compression is the name of the codec that compresses the files which
+	// do not match any of the methods (deflate by default)
+	Compression string
+	// Precompression adds precompressed variants of the matching files that
+	// can be served by the Handler
+	Precompression *Precompression
+	// Manifest adds a manifest that maps the paths of the resources to their
+	// fingerprinted paths (see ManifestName)
+	Manifest bool
+	// PrivateKey signs the bundle if it is set (see ResourceManager.Verify)
+	PrivateKey ed25519.PrivateKey
+	// Symlinks determines how the symbolic links are handled (SymlinkFollow
+	// by default)
+	Symlinks SymlinkPolicy
+	// ContentTypes provides the media types of the files that match given
+	// patterns (the first match wins). The media types of the other files
+	// are detected by their extensions and content.
+	ContentTypes []ContentType
+	// Metadata returns the custom metadata of the resources that is exposed
+	// by the ResourceManager (see MetadataOf)
+	Metadata MetadataFunc
+	// Reproducible produces byte-identical bundles for identical resources.
+	// The entries are sorted, the modification times are set to
+	// SOURCE_DATE_EPOCH (1980-01-01 by default), the extra fields are
+	// stripped and the permissions are fixed (only the executable bit is
+	// preserved).
+	Reproducible bool
+}
+
+// Precompression controls which precompressed variants are added to the bundle
+type Precompression struct {
+	// Patterns of the files that are precompressed (for instance *.js)
+	Patterns []string
+	// Encodings are the names of the content encodings of the variants
+	// (by default br, zstd and gzip)
+	Encodings []string
+}
+
+// CompressionMethod associates the files that match the pattern with a
+// compression method
+type CompressionMethod struct {
+	// Pattern is matched against the path and the name of the file
+	Pattern string
+	// Method is the zip compression method (for instance zip.Store)
+	Method uint16
 }
 
 // ZipCompressor compresses content as GZip tarball
@@ -35,57 +101,108 @@ type ZipCompressor struct {
 // Compress compresses given source in tar.gz
 func (e *ZipCompressor) Compress(ctx *CompressorContext) (*Bundle, error) {
 	buffer := &bytes.Buffer{}
-	count, err := e.write(buffer, ctx)
+	files, err := e.write(buffer, ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if count == 0 {
+	if len(files) == 0 {
 		return nil, nil
 	}
 
 	return &Bundle{
 		Name:  e.Config.Filename,
 		Body:  buffer.Bytes(),
-		Count: count,
+		Count: len(files),
+		Files: files,
 	}, nil
 }
 
-func (e *ZipCompressor) write(w io.Writer, ctx *CompressorContext) (int, error) {
-	compressor := zip.NewWriter(w)
-	if ctx.Offset > 0 {
-		compressor.SetOffset(ctx.Offset)
-	}
+// Inspect returns the bundle of given source without its body. Its
+// fingerprint identifies the content of the resources and the configuration.
+func (e *ZipCompressor) Inspect(ctx *CompressorContext) (*Bundle, error) {
+	checksums := map[string]string{}
+	attributes := map[string]string{}
+
+	files, err := e.Config.traverse(ctx.FileSystem, func(path string, info os.FileInfo) error {
+		checksum, err := contentHash(ctx.FileSystem, path)
+
+		if isSymlink(info) {
+			var target string
 
-	count := 0
+			if target, err = readlink(ctx.FileSystem, path); err == nil {
+				checksum, err = hash(strings.NewReader(target))
+			}
+		}
 
-	err := ctx.FileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		err = e.filter(path, info)
+		checksums[path] = checksum
+		attributes[path] = e.Config.attributes(path, info)
+		return nil
+	})
 
-		switch err {
-		case ErrSkipResource:
-			return nil
-		default:
-			if err != nil {
-				return err
-			}
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	return e.Config.inspect(files, checksums, attributes)
+}
+
+func (e *ZipCompressor) write(w io.Writer, ctx *CompressorContext) ([]string, error) {
+	compressor := &zipWriter{
+		Writer:       zip.NewWriter(w),
+		entries:      map[string]string{},
+		reproducible: e.Config.Reproducible,
+	}
+
+	if e.Config.Reproducible {
+		modTime, err := sourceDateEpoch()
+		if err != nil {
+			return nil, err
 		}
 
-		if err = e.walk(compressor, ctx.FileSystem, path, info); err != nil {
+		compressor.modTime = modTime
+	}
+
+	registerCompressors(compressor.Writer)
+
+	if ctx.Offset > 0 {
+		compressor.SetOffset(ctx.Offset)
+	}
+
+	assets := manifest{}
+
+	files, err := e.Config.traverse(ctx.FileSystem, func(path string, info os.FileInfo) error {
+		checksum, err := e.walk(compressor, ctx.FileSystem, path, info)
+		if err != nil {
 			return err
 		}
 
-		count = count + 1
-		return nil
+		assets["/"+path] = "/" + fingerprint(path, checksum)
+
+		return e.precompress(compressor, ctx.FileSystem, path, info)
 	})
 
 	if err != nil {
-		return count, err
+		return files, err
+	}
+
+	if e.Config.Manifest && len(files) > 0 {
+		if err = e.manifest(compressor, assets); err != nil {
+			return files, err
+		}
+	}
+
+	if key := e.Config.PrivateKey; key != nil && len(files) > 0 {
+		fmt.Fprintln(e.Config.Logger, "Signing the bundle")
+
+		if err = compressor.SetComment(sign(compressor.entries, key)); err != nil {
+			return files, err
+		}
 	}
 
 	_ = compressor.Flush()
@@ -94,20 +211,82 @@ func (e *ZipCompressor) write(w io.Writer, ctx *CompressorContext) (int, error)
 		err = ioErr
 	}
 
-	return count, err
+	return files, err
+}
+
+// EmbedCompressor collects the resources that should be embedded with the
+// go:embed directive. It does not compress them, the produced bundle has no
+// body but only the paths of the resources.
+type EmbedCompressor struct {
+	// Config controls how the resources are collected
+	Config *CompressorConfig
 }
 
-func (e *ZipCompressor) walk(compressor *zip.Writer, fileSystem FileSystem, path string, info os.FileInfo) error {
+// Compress collects the paths of the resources in given source
+func (e *EmbedCompressor) Compress(ctx *CompressorContext) (*Bundle, error) {
+	files, err := e.traverse(ctx.FileSystem, func(path string, info os.FileInfo) error {
+		fmt.Fprintln(e.Config.Logger, fmt.Sprintf("Embedding '%s'", path))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	return &Bundle{
+		Name:  e.Config.Filename,
+		Count: len(files),
+		Files: files,
+	}, nil
+}
+
+// Inspect returns the bundle of given source without its body. Its
+// fingerprint identifies the paths of the resources and the configuration,
+// because the content is embedded by the Go compiler.
+func (e *EmbedCompressor) Inspect(ctx *CompressorContext) (*Bundle, error) {
+	files, err := e.traverse(ctx.FileSystem, func(path string, info os.FileInfo) error {
+		return nil
+	})
+
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	return e.Config.inspect(files, nil, nil)
+}
+
+// traverse walks the resources. The symbolic links are reported as errors,
+// because go:embed cannot embed them.
+func (e *EmbedCompressor) traverse(fileSystem FileSystem, fn func(path string, info os.FileInfo) error) ([]string, error) {
+	cfg := *e.Config
+	cfg.Symlinks = SymlinkError
+	return cfg.traverse(fileSystem, fn)
+}
+
+func (e *ZipCompressor) walk(compressor *zipWriter, fileSystem FileSystem, path string, info os.FileInfo) (string, error) {
 	fmt.Fprintln(e.Config.Logger, fmt.Sprintf("Compressing '%s'", path))
 
-	header, _ := zip.FileInfoHeader(info)
-	header.Method = zip.Deflate
-	header.Name = path
+	method, err := e.Config.method(path, info)
+	if err != nil {
+		return "", err
+	}
+
+	if isSymlink(info) {
+		return e.link(compressor, fileSystem, path, info, method)
+	}
+
+	metadata, err := e.metadata(fileSystem, path, info)
+	if err != nil {
+		return "", err
+	}
 
-	writer, _ := compressor.CreateHeader(header)
 	resource, err := fileSystem.OpenFile(path, os.O_RDONLY, 0)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer func() {
@@ -116,44 +295,435 @@ func (e *ZipCompressor) walk(compressor *zip.Writer, fileSystem FileSystem, path
 		}
 	}()
 
-	_, err = io.Copy(writer, resource)
+	checksum, err := fileHash(path, resource)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := zip.FileInfoHeader(info)
+	header.Method = method
+	header.Name = path
+
+	writer, err := compressor.create(header, checksum, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = io.Copy(writer, resource); err != nil {
+		return "", err
+	}
+
+	return checksum, nil
+}
+
+// metadata returns the metadata of the resource: its content type and the
+// custom metadata (which may override the content type)
+func (e *ZipCompressor) metadata(fileSystem FileSystem, path string, info os.FileInfo) (Metadata, error) {
+	contentType, err := e.Config.contentType(fileSystem, path, info)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := Metadata{MetadataContentType: contentType}
+
+	for key, value := range e.Config.metadata(path, info) {
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
+
+// link adds a symlink entry that contains the destination of the link
+func (e *ZipCompressor) link(compressor *zipWriter, fileSystem FileSystem, path string, info os.FileInfo, method uint16) (string, error) {
+	target, err := readlink(fileSystem, path)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := hash(strings.NewReader(target))
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := zip.FileInfoHeader(info)
+	header.Method = method
+	header.Name = path
+
+	writer, err := compressor.create(header, checksum, e.Config.metadata(path, info))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.WriteString(writer, target)
+	return checksum, err
+}
+
+func (e *ZipCompressor) manifest(compressor *zipWriter, assets manifest) error {
+	if _, ok := assets["/"+ManifestName]; ok {
+		return fmt.Errorf("Resource '%s' conflicts with the manifest", ManifestName)
+	}
+
+	fmt.Fprintln(e.Config.Logger, fmt.Sprintf("Writing manifest '%s'", ManifestName))
+
+	data, err := encodeManifest(assets)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := hash(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	header := &zip.FileHeader{
+		Name:   ManifestName,
+		Method: zip.Deflate,
+	}
+
+	writer, err := compressor.create(header, checksum, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(data)
 	return err
 }
 
-func (e *ZipCompressor) filter(path string, info os.FileInfo) error {
-	if info == nil {
-		return ErrSkipResource
+func (e *ZipCompressor) precompress(compressor *zipWriter, fileSystem FileSystem, path string, info os.FileInfo) error {
+	cfg := e.Config.Precompression
+	if cfg == nil {
+		return nil
 	}
 
-	if err := e.ignore(path, info); err != nil {
+	encodings, err := lookupEncodings(cfg.Encodings)
+	if err != nil {
 		return err
 	}
 
-	if !info.IsDir() {
+	if isEncoded(path, encodings) || isSymlink(info) {
 		return nil
 	}
 
-	if !e.Config.Recurive && path != "." {
-		return filepath.SkipDir
+	matched := false
+
+	for _, pattern := range cfg.Patterns {
+		if matched, err = match(pattern, path, info.Name()); err != nil {
+			return err
+		}
+
+		if matched {
+			break
+		}
 	}
 
-	return ErrSkipResource
+	if !matched {
+		return nil
+	}
+
+	for _, encoding := range encodings {
+		name := path + encoding.Extension
+
+		// the variant exists in the file system and it is compressed as it is
+		if file, err := fileSystem.Open(name); err == nil {
+			file.Close()
+			continue
+		}
+
+		fmt.Fprintln(e.Config.Logger, fmt.Sprintf("Precompressing '%s'", name))
+
+		header, _ := zip.FileInfoHeader(info)
+		header.Method = zip.Store
+		header.Name = name
+
+		if err := e.variant(compressor, fileSystem, path, header, encoding); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *ZipCompressor) variant(compressor *zipWriter, fileSystem FileSystem, path string, header *zip.FileHeader, encoding *ContentEncoding) error {
+	resource, err := fileSystem.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	defer resource.Close()
+
+	buffer := &bytes.Buffer{}
+
+	writer, err := encoding.Compressor(buffer)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(writer, resource); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	checksum, err := hash(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		return err
+	}
+
+	entry, err := compressor.create(header, checksum, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, buffer)
+	return err
+}
+
+// zipWriter records the signed attributes of the written entries
+type zipWriter struct {
+	*zip.Writer
+	entries      map[string]string
+	reproducible bool
+	modTime      time.Time
+}
+
+// create adds an entry with given checksum and metadata to the archive
+func (w *zipWriter) create(header *zip.FileHeader, checksum string, metadata Metadata) (io.Writer, error) {
+	values := metadata.values()
+	values.Set(metadataHash, checksum)
+
+	header.Comment = encodeMetadata(values)
+
+	if w.reproducible {
+		normalize(header, w.modTime)
+	}
+
+	writer, err := w.CreateHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	w.entries[header.Name] = signedEntry(checksum, header)
+	return writer, nil
+}
+
+// normalize removes the attributes of the header that depend on the machine
+// which creates the archive
+func normalize(header *zip.FileHeader, modTime time.Time) {
+	mode := os.FileMode(0644)
+
+	switch {
+	case header.Mode()&os.ModeSymlink != 0:
+		mode = os.ModeSymlink | 0777
+	case header.Mode()&0111 != 0:
+		mode = 0755
+	}
+
+	// the zero time prevents the writer from adding the extended timestamp
+	header.Modified = time.Time{}
+	header.ModifiedDate = uint16(modTime.Day() + int(modTime.Month())<<5 + (modTime.Year()-1980)<<9)
+	header.ModifiedTime = uint16(modTime.Second()/2 + modTime.Minute()<<5 + modTime.Hour()<<11)
+	header.Extra = nil
+	header.CreatorVersion = 0
+	header.ExternalAttrs = 0
+	header.SetMode(mode)
+}
+
+// sourceDateEpoch returns the modification time of the reproducible entries.
+// It is set by SOURCE_DATE_EPOCH environment variable.
+func sourceDateEpoch() (time.Time, error) {
+	// the earliest time that can be represented in the archive
+	epoch := time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return epoch, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid SOURCE_DATE_EPOCH '%s'", value)
+	}
+
+	modTime := time.Unix(seconds, 0).UTC()
+
+	if modTime.Before(epoch) {
+		return epoch, nil
+	}
+
+	return modTime, nil
+}
+
+// inspect returns the bundle of the files with given checksums and attributes
+func (cfg *CompressorConfig) inspect(files []string, checksums, attributes map[string]string) (*Bundle, error) {
+	options := struct {
+		IgnorePatterns  []string
+		IncludePatterns []string
+		Recurive        bool
+		Methods         []CompressionMethod
+		ContentTypes    []ContentType
+		Compression     string
+		Precompression  *Precompression
+		Manifest        bool
+		Signer          string
+		Symlinks        SymlinkPolicy
+		Reproducible    bool
+		SourceDateEpoch string
+		Files           []string
+		Checksums       map[string]string
+		Attributes      map[string]string
+	}{
+		IgnorePatterns:  cfg.IgnorePatterns,
+		IncludePatterns: cfg.IncludePatterns,
+		Recurive:        cfg.Recurive,
+		Methods:         cfg.Methods,
+		ContentTypes:    cfg.ContentTypes,
+		Compression:     cfg.Compression,
+		Precompression:  cfg.Precompression,
+		Manifest:        cfg.Manifest,
+		Symlinks:        cfg.Symlinks,
+		Reproducible:    cfg.Reproducible,
+		Files:           files,
+		Checksums:       checksums,
+		Attributes:      attributes,
+	}
+
+	if cfg.PrivateKey != nil {
+		options.Signer = hex.EncodeToString(cfg.PrivateKey.Public().(ed25519.PublicKey))
+	}
+
+	if cfg.Reproducible {
+		options.SourceDateEpoch = os.Getenv("SOURCE_DATE_EPOCH")
+	}
+
+	data, err := json.Marshal(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(data)
+
+	return &Bundle{
+		Name:        cfg.Filename,
+		Count:       len(files),
+		Files:       files,
+		Fingerprint: hex.EncodeToString(checksum[:]),
+	}, nil
 }
 
-func (e *ZipCompressor) ignore(path string, info os.FileInfo) error {
-	ignore := append(e.Config.IgnorePatterns, "*.go")
+// metadata returns the custom metadata of the resource
+func (cfg *CompressorConfig) metadata(path string, info os.FileInfo) Metadata {
+	if cfg.Metadata == nil {
+		return nil
+	}
+
+	return cfg.Metadata(path, info)
+}
+
+// attributes returns the attributes of the resource that are bundled with
+// its content (the permissions and the metadata)
+func (cfg *CompressorConfig) attributes(path string, info os.FileInfo) string {
+	values := cfg.metadata(path, info).values()
+	values.Set("mode", strconv.FormatUint(uint64(info.Mode().Perm()), 8))
+	return encodeMetadata(values)
+}
+
+// traverse walks the file system and calls fn for every resource that has
+// not been ignored. It returns the paths of all visited resources.
+func (cfg *CompressorConfig) traverse(fileSystem FileSystem, fn func(path string, info os.FileInfo) error) ([]string, error) {
+	if !cfg.Reproducible {
+		return cfg.visit(fileSystem, fn)
+	}
+
+	infos := map[string]os.FileInfo{}
+
+	files, err := cfg.visit(fileSystem, func(path string, info os.FileInfo) error {
+		infos[path] = info
+		return nil
+	})
+
+	if err != nil {
+		return files, err
+	}
 
-	for _, pattern := range ignore {
-		matched, err := match(pattern, path, info.Name())
+	// the resources are processed in the order of their paths
+	sort.Strings(files)
+
+	for index, path := range files {
+		if err := fn(path, infos[path]); err != nil {
+			return files[:index], err
+		}
+	}
+
+	return files, nil
+}
 
+// visit calls fn for every resource in the order of the file system walk
+func (cfg *CompressorConfig) visit(fileSystem FileSystem, fn func(path string, info os.FileInfo) error) ([]string, error) {
+	files := []string{}
+
+	ignorer, err := cfg.ignorer(fileSystem)
+	if err != nil {
+		return files, err
+	}
+
+	// links is the number of the followed symbolic links in the current path
+	links := 0
+
+	var walkFn filepath.WalkFunc
+
+	walkFn = func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !matched {
-			continue
+		link := isSymlink(info)
+
+		if link {
+			if info, err = cfg.symlink(fileSystem, path, info); err != nil {
+				return err
+			}
+		}
+
+		err = cfg.filter(ignorer, path, info)
+
+		switch {
+		case err == ErrSkipResource && link && info.IsDir():
+			if links++; links > maxLinks {
+				return &os.PathError{Op: "walk", Path: path, Err: ErrTooManyLinks}
+			}
+
+			err = walkLink(fileSystem, path, walkFn)
+			links--
+			return err
+		case err == ErrSkipResource:
+			return nil
+		case err == filepath.SkipDir && link:
+			// the walker treats the link as a file
+			return nil
+		case err != nil:
+			return err
 		}
 
+		if err = fn(path, info); err != nil {
+			return err
+		}
+
+		files = append(files, path)
+		return nil
+	}
+
+	err = fileSystem.Walk("/", walkFn)
+	return files, err
+}
+
+func (cfg *CompressorConfig) filter(ignorer *ignorer, path string, info os.FileInfo) error {
+	if info == nil {
+		return ErrSkipResource
+	}
+
+	if ignorer.ignored(path, info) {
 		if info.IsDir() {
 			return filepath.SkipDir
 		}
@@ -161,5 +731,42 @@ func (e *ZipCompressor) ignore(path string, info os.FileInfo) error {
 		return ErrSkipResource
 	}
 
-	return nil
+	if !info.IsDir() {
+		return nil
+	}
+
+	if !cfg.Recurive && slashPath(path) != "." {
+		return filepath.SkipDir
+	}
+
+	if err := ignorer.load(path); err != nil {
+		return err
+	}
+
+	return ErrSkipResource
+}
+
+func (cfg *CompressorConfig) method(path string, info os.FileInfo) (uint16, error) {
+	for _, rule := range cfg.Methods {
+		matched, err := match(rule.Pattern, path, info.Name())
+
+		if err != nil {
+			return 0, err
+		}
+
+		if matched {
+			return rule.Method, nil
+		}
+	}
+
+	if cfg.Compression == "" {
+		return zip.Deflate, nil
+	}
+
+	codec, err := LookupCodec(cfg.Compression)
+	if err != nil {
+		return 0, err
+	}
+
+	return codec.Method, nil
 }
@@ -4,7 +4,10 @@ import (
 	"archive/zip"
 	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
+	"testing/fstest"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -128,6 +131,206 @@ var _ = Describe("ZipCompressor", func() {
 		})
 	})
 
+	Context("when the compression method is provided", func() {
+		It("compresses the matching files with that method", func() {
+			compressor.Config.Methods = []parcello.CompressionMethod{
+				{Pattern: "*.txt", Method: zip.Store},
+			}
+
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			}
+
+			bundle, err := compressor.Compress(ctx)
+			Expect(err).To(BeNil())
+
+			reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+			Expect(err).To(BeNil())
+
+			Expect(reader.File[0].Name).To(Equal("resource/reports/2018.txt"))
+			Expect(reader.File[0].Method).To(Equal(zip.Store))
+			Expect(reader.File[1].Name).To(Equal("resource/scripts/schema.sql"))
+			Expect(reader.File[1].Method).To(Equal(zip.Deflate))
+		})
+
+		Context("when the method is not supported", func() {
+			It("returns an error", func() {
+				compressor.Config.Methods = []parcello.CompressionMethod{
+					{Pattern: "*.txt", Method: 2000},
+				}
+
+				ctx := &parcello.CompressorContext{
+					FileSystem: parcello.Dir("./fixture"),
+				}
+
+				bundle, err := compressor.Compress(ctx)
+				Expect(err).To(MatchError(zip.ErrAlgorithm))
+				Expect(bundle).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the precompression is enabled", func() {
+		It("adds the precompressed variants", func() {
+			compressor.Config.Precompression = &parcello.Precompression{
+				Patterns:  []string{"*.html"},
+				Encodings: []string{"gzip"},
+			}
+
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			}
+
+			bundle, err := compressor.Compress(ctx)
+			Expect(err).To(BeNil())
+			Expect(bundle.Count).To(Equal(4))
+
+			reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+			Expect(err).To(BeNil())
+
+			Expect(reader.File).To(HaveLen(5))
+			Expect(reader.File[2].Name).To(Equal("resource/templates/html/index.html"))
+			Expect(reader.File[3].Name).To(Equal("resource/templates/html/index.html.gz"))
+			Expect(reader.File[3].Method).To(Equal(zip.Store))
+		})
+
+		Context("when the encoding is not supported", func() {
+			It("returns an error", func() {
+				compressor.Config.Precompression = &parcello.Precompression{
+					Patterns:  []string{"*.html"},
+					Encodings: []string{"lz4"},
+				}
+
+				ctx := &parcello.CompressorContext{
+					FileSystem: parcello.Dir("./fixture"),
+				}
+
+				bundle, err := compressor.Compress(ctx)
+				Expect(err).To(MatchError("Unsupported content encoding 'lz4'"))
+				Expect(bundle).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the output is reproducible", func() {
+		compress := func(modTime time.Time, mode os.FileMode) []byte {
+			source := parcello.FromFS(fstest.MapFS{
+				"templates/index.html": &fstest.MapFile{Data: []byte("<html></html>"), ModTime: modTime, Mode: mode},
+				"css/app.css":          &fstest.MapFile{Data: []byte("body {}"), ModTime: modTime, Mode: mode},
+			})
+
+			fileSystem := &fake.FileSystem{}
+			fileSystem.OpenStub = source.Open
+			fileSystem.OpenFileStub = source.OpenFile
+			fileSystem.WalkStub = func(dir string, fn filepath.WalkFunc) error {
+				// the resources are not walked in the order of their paths
+				for _, name := range []string{"templates/index.html", "css/app.css"} {
+					info, err := source.Stat(name)
+					Expect(err).NotTo(HaveOccurred())
+
+					if err := fn(name, info, nil); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			}
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{FileSystem: fileSystem})
+			Expect(err).NotTo(HaveOccurred())
+			return bundle.Body
+		}
+
+		BeforeEach(func() {
+			compressor.Config.Reproducible = true
+			compressor.Config.Manifest = true
+			compressor.Config.Precompression = &parcello.Precompression{Patterns: []string{"*.css"}}
+		})
+
+		It("produces identical bundles", func() {
+			first := compress(time.Now(), 0600)
+			second := compress(time.Now().Add(-time.Hour), 0664)
+			Expect(first).To(Equal(second))
+		})
+
+		It("preserves the executable bit", func() {
+			body := compress(time.Now(), 0700)
+
+			reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			Expect(err).To(BeNil())
+
+			for _, file := range reader.File {
+				if file.Name == parcello.ManifestName {
+					continue
+				}
+
+				Expect(file.Mode()).To(Equal(os.FileMode(0755)))
+			}
+		})
+
+		It("normalizes the entries", func() {
+			body := compress(time.Now(), 0600)
+
+			reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			Expect(err).To(BeNil())
+
+			names := []string{}
+
+			for _, file := range reader.File {
+				names = append(names, file.Name)
+
+				Expect(file.Extra).To(BeEmpty())
+				Expect(file.Mode()).To(Equal(os.FileMode(0644)))
+				Expect(file.Modified).To(Equal(time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)))
+			}
+
+			Expect(names).To(Equal([]string{
+				"css/app.css",
+				"css/app.css.br",
+				"css/app.css.zst",
+				"css/app.css.gz",
+				"templates/index.html",
+				parcello.ManifestName,
+			}))
+		})
+
+		Context("when SOURCE_DATE_EPOCH is set", func() {
+			BeforeEach(func() {
+				os.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("SOURCE_DATE_EPOCH")
+			})
+
+			It("sets the modification time", func() {
+				body := compress(time.Now(), 0600)
+
+				reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+				Expect(err).To(BeNil())
+
+				for _, file := range reader.File {
+					Expect(file.Modified.Equal(time.Unix(1600000000, 0))).To(BeTrue())
+				}
+			})
+
+			Context("when the value is invalid", func() {
+				BeforeEach(func() {
+					os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+				})
+
+				It("returns an error", func() {
+					bundle, err := compressor.Compress(&parcello.CompressorContext{
+						FileSystem: parcello.Dir("./fixture"),
+					})
+
+					Expect(err).To(MatchError("Invalid SOURCE_DATE_EPOCH 'yesterday'"))
+					Expect(bundle).To(BeNil())
+				})
+			})
+		})
+	})
+
 	Context("when the pattern is invalid", func() {
 		It("returns an error", func() {
 			compressor.Config.IgnorePatterns = []string{"[*"}
@@ -221,4 +424,207 @@ var _ = Describe("ZipCompressor", func() {
 			Expect(bundle).To(BeNil())
 		})
 	})
+
+	Describe("Inspect", func() {
+		It("fingerprints the resources without compressing them", func() {
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			}
+
+			bundle, err := compressor.Inspect(ctx)
+			Expect(err).To(BeNil())
+			Expect(bundle).NotTo(BeNil())
+			Expect(bundle.Name).To(Equal("bundle"))
+			Expect(bundle.Body).To(BeEmpty())
+			Expect(bundle.Count).To(Equal(4))
+			Expect(bundle.Fingerprint).To(HaveLen(64))
+
+			other, err := compressor.Inspect(ctx)
+			Expect(err).To(BeNil())
+			Expect(other.Fingerprint).To(Equal(bundle.Fingerprint))
+		})
+
+		Context("when the configuration changes", func() {
+			It("changes the fingerprint", func() {
+				ctx := &parcello.CompressorContext{
+					FileSystem: parcello.Dir("./fixture"),
+				}
+
+				bundle, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+
+				compressor.Config.Compression = "store"
+
+				other, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+				Expect(other.Fingerprint).NotTo(Equal(bundle.Fingerprint))
+			})
+		})
+
+		Context("when the content changes", func() {
+			It("changes the fingerprint", func() {
+				fileSystem := fstest.MapFS{
+					"index.html": &fstest.MapFile{Data: []byte("hello")},
+				}
+
+				ctx := &parcello.CompressorContext{
+					FileSystem: parcello.FromFS(fileSystem),
+				}
+
+				bundle, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+
+				fileSystem["index.html"].Data = []byte("world")
+
+				other, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+				Expect(other.Fingerprint).NotTo(Equal(bundle.Fingerprint))
+			})
+		})
+
+		Context("when the attributes change", func() {
+			It("changes the fingerprint", func() {
+				fileSystem := fstest.MapFS{
+					"migrate.sh": &fstest.MapFile{Data: []byte("#!/bin/sh"), Mode: 0644},
+				}
+
+				ctx := &parcello.CompressorContext{
+					FileSystem: parcello.FromFS(fileSystem),
+				}
+
+				bundle, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+
+				fileSystem["migrate.sh"].Mode = 0755
+
+				other, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+				Expect(other.Fingerprint).NotTo(Equal(bundle.Fingerprint))
+
+				compressor.Config.Metadata = func(path string, info os.FileInfo) parcello.Metadata {
+					return parcello.Metadata{"owner": "ops"}
+				}
+
+				last, err := compressor.Inspect(ctx)
+				Expect(err).To(BeNil())
+				Expect(last.Fingerprint).NotTo(Equal(other.Fingerprint))
+			})
+		})
+
+		Context("when opening file fails", func() {
+			It("return the error", func() {
+				fileSystem := &fake.FileSystem{}
+				fileSystem.WalkStub = parcello.Dir("./fixture").Walk
+				fileSystem.OpenFileReturns(nil, os.ErrNotExist)
+				fileSystem.OpenReturns(nil, fmt.Errorf("Oh no!"))
+
+				ctx := &parcello.CompressorContext{
+					FileSystem: fileSystem,
+				}
+
+				bundle, err := compressor.Inspect(ctx)
+				Expect(err).To(MatchError("Oh no!"))
+				Expect(bundle).To(BeNil())
+			})
+		})
+	})
+})
+
+var _ = Describe("EmbedCompressor", func() {
+	var compressor *parcello.EmbedCompressor
+
+	BeforeEach(func() {
+		compressor = &parcello.EmbedCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+	})
+
+	It("collects a given hierarchy", func() {
+		ctx := &parcello.CompressorContext{
+			FileSystem: parcello.Dir("./fixture"),
+		}
+
+		bundle, err := compressor.Compress(ctx)
+		Expect(err).To(BeNil())
+		Expect(bundle).NotTo(BeNil())
+		Expect(bundle.Name).To(Equal("bundle"))
+		Expect(bundle.Body).To(BeEmpty())
+		Expect(bundle.Count).To(Equal(4))
+		Expect(bundle.Files).To(Equal([]string{
+			"resource/reports/2018.txt",
+			"resource/scripts/schema.sql",
+			"resource/templates/html/index.html",
+			"resource/templates/yml/schema.yml",
+		}))
+	})
+
+	Context("when ingore pattern is provided", func() {
+		It("ignores that files", func() {
+			compressor.Config.IgnorePatterns = []string{"resource/templates"}
+
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			}
+
+			bundle, err := compressor.Compress(ctx)
+			Expect(err).To(BeNil())
+			Expect(bundle.Files).To(Equal([]string{
+				"resource/reports/2018.txt",
+				"resource/scripts/schema.sql",
+			}))
+		})
+	})
+
+	Context("when the recursion is disabled", func() {
+		It("does not go through the hierarchy", func() {
+			compressor.Config.Recurive = false
+
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			}
+
+			bundle, err := compressor.Compress(ctx)
+			Expect(err).To(BeNil())
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Context("when the traversing fails", func() {
+		It("return the error", func() {
+			fileSystem := &fake.FileSystem{}
+			fileSystem.WalkReturns(fmt.Errorf("Oh no!"))
+
+			ctx := &parcello.CompressorContext{
+				FileSystem: fileSystem,
+			}
+
+			bundle, err := compressor.Compress(ctx)
+			Expect(err).To(MatchError("Oh no!"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Describe("Inspect", func() {
+		It("fingerprints the paths of the resources", func() {
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.Dir("./fixture"),
+			}
+
+			bundle, err := compressor.Inspect(ctx)
+			Expect(err).To(BeNil())
+			Expect(bundle).NotTo(BeNil())
+			Expect(bundle.Count).To(Equal(4))
+			Expect(bundle.Fingerprint).To(HaveLen(64))
+
+			compressor.Config.IgnorePatterns = []string{"resource/templates"}
+
+			other, err := compressor.Inspect(ctx)
+			Expect(err).To(BeNil())
+			Expect(other.Fingerprint).NotTo(Equal(bundle.Fingerprint))
+		})
+	})
 })
@@ -0,0 +1,131 @@
+package parcello
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// contentTypes contains the media types of the extensions that are missing
+// or inconsistent in the system tables
+var contentTypes = map[string]string{
+	".css":         "text/css; charset=utf-8",
+	".csv":         "text/csv; charset=utf-8",
+	".gif":         "image/gif",
+	".htm":         "text/html; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".ico":         "image/vnd.microsoft.icon",
+	".jpeg":        "image/jpeg",
+	".jpg":         "image/jpeg",
+	".js":          "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".md":          "text/markdown; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".pdf":         "application/pdf",
+	".png":         "image/png",
+	".sql":         "application/sql",
+	".svg":         "image/svg+xml",
+	".toml":        "application/toml",
+	".ts":          "text/typescript; charset=utf-8",
+	".txt":         "text/plain; charset=utf-8",
+	".wasm":        "application/wasm",
+	".webmanifest": "application/manifest+json",
+	".webp":        "image/webp",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".xml":         "text/xml; charset=utf-8",
+	".yaml":        "application/yaml",
+	".yml":         "application/yaml",
+}
+
+// ContentType associates the files that match the pattern with a media type
+type ContentType struct {
+	// Pattern is matched against the path and the name of the file
+	Pattern string
+	// MediaType is the content type of the matching files (for instance
+	// application/wasm)
+	MediaType string
+}
+
+// ParseContentType parses a content type in 'pattern=media-type' format
+func ParseContentType(value string) (ContentType, error) {
+	index := strings.Index(value, "=")
+
+	if index <= 0 || index == len(value)-1 {
+		return ContentType{}, fmt.Errorf("Invalid content type '%s'", value)
+	}
+
+	rule := ContentType{
+		Pattern:   value[:index],
+		MediaType: value[index+1:],
+	}
+
+	if _, _, err := mime.ParseMediaType(rule.MediaType); err != nil {
+		return ContentType{}, fmt.Errorf("Invalid content type '%s': %v", value, err)
+	}
+
+	return rule, nil
+}
+
+// contentType returns the media type of the resource. The content types of
+// the config take precedence over the detected ones.
+func (cfg *CompressorConfig) contentType(fileSystem FileSystem, name string, info os.FileInfo) (string, error) {
+	for _, rule := range cfg.ContentTypes {
+		matched, err := match(rule.Pattern, name, info.Name())
+
+		if err != nil {
+			return "", err
+		}
+
+		if matched {
+			return rule.MediaType, nil
+		}
+	}
+
+	return detectContentType(fileSystem, name)
+}
+
+// detectContentType returns the media type of the named file. It is looked up
+// by the extension of the file and it is detected from the content if the
+// extension is unknown.
+func detectContentType(fileSystem FileSystem, name string) (string, error) {
+	if contentType := typeByExtension(path.Ext(name)); contentType != "" {
+		return contentType, nil
+	}
+
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	buffer := make([]byte, 512)
+
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return http.DetectContentType(buffer[:n]), nil
+}
+
+// typeByExtension returns the media type of the extension
+func typeByExtension(ext string) string {
+	if contentType, ok := contentTypes[strings.ToLower(ext)]; ok {
+		return contentType
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+// ContentType returns the media type of the resource detected by the
+// compressor (empty if it is unknown)
+func (n *ResourceFileInfo) ContentType() string {
+	return n.Metadata().ContentType()
+}
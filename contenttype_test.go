@@ -0,0 +1,125 @@
+package parcello_test
+
+import (
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("ContentType", func() {
+	var (
+		compressor *parcello.ZipCompressor
+		fileSystem fstest.MapFS
+	)
+
+	contentType := func(name string) string {
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.FromFS(fileSystem),
+		})
+		Expect(err).To(BeNil())
+
+		manager := &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+		info, err := manager.Stat(name)
+		Expect(err).To(BeNil())
+
+		return info.(*parcello.ResourceFileInfo).ContentType()
+	}
+
+	BeforeEach(func() {
+		fileSystem = fstest.MapFS{
+			"app.mjs":   &fstest.MapFile{Data: []byte("export default 1;")},
+			"main.wasm": &fstest.MapFile{Data: []byte("\x00asm\x01\x00\x00\x00")},
+			"logo":      &fstest.MapFile{Data: []byte("\x89PNG\x0D\x0A\x1A\x0A")},
+			"VERSION":   &fstest.MapFile{Data: []byte("1.0.0")},
+		}
+
+		compressor = &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+	})
+
+	It("detects the content type by the extension", func() {
+		Expect(contentType("app.mjs")).To(Equal("text/javascript; charset=utf-8"))
+		Expect(contentType("main.wasm")).To(Equal("application/wasm"))
+	})
+
+	It("detects the content type of the files without extension", func() {
+		Expect(contentType("logo")).To(Equal("image/png"))
+		Expect(contentType("VERSION")).To(Equal("text/plain; charset=utf-8"))
+	})
+
+	Context("when the content type is overridden", func() {
+		It("stores the overridden content type", func() {
+			compressor.Config.ContentTypes = []parcello.ContentType{
+				{Pattern: "*.mjs", MediaType: "application/javascript"},
+				{Pattern: "*", MediaType: "application/octet-stream"},
+			}
+
+			Expect(contentType("app.mjs")).To(Equal("application/javascript"))
+			Expect(contentType("VERSION")).To(Equal("application/octet-stream"))
+		})
+	})
+
+	Context("when the metadata contains content type", func() {
+		It("stores the content type of the metadata", func() {
+			compressor.Config.Metadata = func(path string, info os.FileInfo) parcello.Metadata {
+				return parcello.Metadata{parcello.MetadataContentType: "application/x-version"}
+			}
+
+			Expect(contentType("VERSION")).To(Equal("application/x-version"))
+		})
+	})
+
+	Context("when the pattern is invalid", func() {
+		It("returns an error", func() {
+			compressor.Config.ContentTypes = []parcello.ContentType{
+				{Pattern: "[*", MediaType: "text/plain"},
+			}
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.FromFS(fileSystem),
+			})
+			Expect(err).To(MatchError("syntax error in pattern"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("ParseContentType", func() {
+	It("parses the pattern and the media type", func() {
+		rule, err := parcello.ParseContentType("*.wasm=application/wasm")
+		Expect(err).To(BeNil())
+		Expect(rule.Pattern).To(Equal("*.wasm"))
+		Expect(rule.MediaType).To(Equal("application/wasm"))
+	})
+
+	It("parses the media type with parameters", func() {
+		rule, err := parcello.ParseContentType("LICENSE=text/plain; charset=utf-8")
+		Expect(err).To(BeNil())
+		Expect(rule.Pattern).To(Equal("LICENSE"))
+		Expect(rule.MediaType).To(Equal("text/plain; charset=utf-8"))
+	})
+
+	Context("when the media type is missing", func() {
+		It("returns an error", func() {
+			_, err := parcello.ParseContentType("*.wasm")
+			Expect(err).To(MatchError("Invalid content type '*.wasm'"))
+		})
+	})
+
+	Context("when the media type is invalid", func() {
+		It("returns an error", func() {
+			_, err := parcello.ParseContentType("*.wasm=/")
+			Expect(err).To(MatchError(HavePrefix("Invalid content type '*.wasm=/'")))
+		})
+	})
+})
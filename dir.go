@@ -1,6 +1,8 @@
 package parcello
 
 import (
+	"io/fs"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 )
@@ -53,3 +55,24 @@ func (d Dir) Dir(name string) (FileSystemManager, error) {
 func (d Dir) Add(resource *Resource) error {
 	return nil
 }
+
+// AssetPath returns the fingerprinted path of the named resource
+func (d Dir) AssetPath(name string) (string, error) {
+	return Fingerprint(d, name)
+}
+
+// Stat returns a FileInfo describing the named file
+func (d Dir) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(filepath.Join(string(d), name))
+}
+
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename
+func (d Dir) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.Join(string(d), name))
+}
+
+// ReadFile reads the named file and returns its contents
+func (d Dir) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(d), name))
+}
@@ -61,11 +61,13 @@ var _ = Describe("Dir", func() {
 
 		Context("when the underlying file system fails", func() {
 			It("returns an error", func() {
-				dir = parcello.Dir("/hello")
+				// the root is a file, so the directory cannot be created
+				// regardless of the permissions of the user
+				dir = parcello.Dir(filepath.Join(string(dir), "sample.txt"))
 				file, err := dir.OpenFile("report.txt", os.O_CREATE, 0)
 				Expect(file).To(BeNil())
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("mkdir /hello: permission denied"))
+				Expect(err.Error()).To(ContainSubstring("not a directory"))
 			})
 		})
 
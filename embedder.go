@@ -15,6 +15,22 @@ type Embedder struct {
 	Compressor Compressor
 	// FileSystem represents the underlying file system
 	FileSystem FileSystem
+	// Force composes the resources even if they have not changed
+	Force bool
+}
+
+// inspector is implemented by the compressors that can fingerprint the
+// resources without compressing them
+type inspector interface {
+	// Inspect returns the bundle without its body
+	Inspect(ctx *CompressorContext) (*Bundle, error)
+}
+
+// incremental is implemented by the composers that record the fingerprint
+// of the composed bundle
+type incremental interface {
+	// UpToDate returns true if the bundle has been composed already
+	UpToDate(bundle *Bundle) (bool, error)
 }
 
 // Embed embeds the resources to the provided package
@@ -23,6 +39,11 @@ func (e *Embedder) Embed() error {
 		FileSystem: e.FileSystem,
 	}
 
+	fingerprint, changed, err := e.fingerprint(ctx)
+	if err != nil || !changed {
+		return err
+	}
+
 	bundle, err := e.Compressor.Compress(ctx)
 	if err != nil {
 		return err
@@ -32,7 +53,47 @@ func (e *Embedder) Embed() error {
 		return nil
 	}
 
+	bundle.Fingerprint = fingerprint
+
 	fmt.Fprintf(e.Logger, "Embedding %d resource(s) at 'resource.go'\n", bundle.Count)
 	err = e.Composer.Compose(bundle)
 	return err
 }
+
+// fingerprint returns the fingerprint of the resources and whether they have
+// changed since they were composed. The fingerprint is empty if the
+// compressor or the composer does not support it.
+func (e *Embedder) fingerprint(ctx *CompressorContext) (string, bool, error) {
+	compressor, ok := e.Compressor.(inspector)
+	if !ok {
+		return "", true, nil
+	}
+
+	composer, ok := e.Composer.(incremental)
+	if !ok {
+		return "", true, nil
+	}
+
+	bundle, err := compressor.Inspect(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	if bundle == nil {
+		return "", false, nil
+	}
+
+	if !e.Force {
+		upToDate, err := composer.UpToDate(bundle)
+		if err != nil {
+			return "", false, err
+		}
+
+		if upToDate {
+			fmt.Fprintf(e.Logger, "Skipping '%s.go', the resources have not changed\n", bundle.Name)
+			return bundle.Fingerprint, false, nil
+		}
+	}
+
+	return bundle.Fingerprint, true, nil
+}
@@ -90,4 +90,100 @@ var _ = Describe("Embedder", func() {
 			Expect(embedder.Embed()).To(MatchError("Oh no!"))
 		})
 	})
+
+	Context("when the compressor and the composer are incremental", func() {
+		var (
+			inspector   *inspectCompressor
+			incremental *incrementalComposer
+		)
+
+		BeforeEach(func() {
+			inspector = &inspectCompressor{
+				Compressor: compressor,
+				bundle: &parcello.Bundle{
+					Name:        "resource",
+					Count:       20,
+					Fingerprint: "fingerprint",
+				},
+			}
+
+			incremental = &incrementalComposer{Composer: composer}
+
+			embedder.Compressor = inspector
+			embedder.Composer = incremental
+		})
+
+		It("composes the bundle with its fingerprint", func() {
+			Expect(embedder.Embed()).To(Succeed())
+			Expect(compressor.CompressCallCount()).To(Equal(1))
+			Expect(composer.ComposeCallCount()).To(Equal(1))
+			Expect(composer.ComposeArgsForCall(0).Fingerprint).To(Equal("fingerprint"))
+		})
+
+		Context("when the resources have not changed", func() {
+			BeforeEach(func() {
+				incremental.upToDate = true
+			})
+
+			It("does not compress and compose them", func() {
+				Expect(embedder.Embed()).To(Succeed())
+				Expect(compressor.CompressCallCount()).To(BeZero())
+				Expect(composer.ComposeCallCount()).To(BeZero())
+			})
+
+			Context("when the embedding is forced", func() {
+				It("composes the bundle", func() {
+					embedder.Force = true
+
+					Expect(embedder.Embed()).To(Succeed())
+					Expect(compressor.CompressCallCount()).To(Equal(1))
+					Expect(composer.ComposeCallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("when there are no resources", func() {
+			It("does not compress them", func() {
+				inspector.bundle = nil
+
+				Expect(embedder.Embed()).To(Succeed())
+				Expect(compressor.CompressCallCount()).To(BeZero())
+				Expect(composer.ComposeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the inspection fails", func() {
+			It("returns the error", func() {
+				inspector.err = fmt.Errorf("Oh no!")
+				Expect(embedder.Embed()).To(MatchError("Oh no!"))
+			})
+		})
+
+		Context("when the composer cannot read the fingerprint", func() {
+			It("returns the error", func() {
+				incremental.err = fmt.Errorf("Oh no!")
+				Expect(embedder.Embed()).To(MatchError("Oh no!"))
+			})
+		})
+	})
 })
+
+type inspectCompressor struct {
+	parcello.Compressor
+	bundle *parcello.Bundle
+	err    error
+}
+
+func (c *inspectCompressor) Inspect(ctx *parcello.CompressorContext) (*parcello.Bundle, error) {
+	return c.bundle, c.err
+}
+
+type incrementalComposer struct {
+	parcello.Composer
+	upToDate bool
+	err      error
+}
+
+func (c *incrementalComposer) UpToDate(bundle *parcello.Bundle) (bool, error) {
+	return c.upToDate, c.err
+}
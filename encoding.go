@@ -0,0 +1,144 @@
+package parcello
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ContentEncoding represents an HTTP content encoding of the precompressed
+// variants of the resources
+type ContentEncoding struct {
+	// Name is the value of Content-Encoding header
+	Name string
+	// Extension is the suffix of the variant file name
+	Extension string
+	// Compressor compresses the variant
+	Compressor func(w io.Writer) (io.WriteCloser, error)
+}
+
+// ContentEncodings are the supported content encodings in order of preference
+var ContentEncodings = []*ContentEncoding{
+	{
+		Name:      "br",
+		Extension: ".br",
+		Compressor: func(w io.Writer) (io.WriteCloser, error) {
+			return brotli.NewWriterLevel(w, brotli.BestCompression), nil
+		},
+	},
+	{
+		Name:      "zstd",
+		Extension: ".zst",
+		Compressor: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		},
+	},
+	{
+		Name:      "gzip",
+		Extension: ".gz",
+		Compressor: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriterLevel(w, gzip.BestCompression)
+		},
+	},
+}
+
+func lookupEncodings(names []string) ([]*ContentEncoding, error) {
+	if len(names) == 0 {
+		return ContentEncodings, nil
+	}
+
+	items := []*ContentEncoding{}
+
+	for _, name := range names {
+		encoding := lookupEncoding(name)
+		if encoding == nil {
+			return nil, fmt.Errorf("Unsupported content encoding '%s'", name)
+		}
+
+		items = append(items, encoding)
+	}
+
+	return items, nil
+}
+
+func lookupEncoding(name string) *ContentEncoding {
+	for _, encoding := range ContentEncodings {
+		if strings.EqualFold(encoding.Name, name) {
+			return encoding
+		}
+	}
+
+	return nil
+}
+
+// isEncoded returns true if the path is a variant of given encodings
+func isEncoded(path string, encodings []*ContentEncoding) bool {
+	for _, encoding := range encodings {
+		if strings.HasSuffix(path, encoding.Extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negotiate returns the most preferred encoding that is accepted by the
+// client as described by the Accept-Encoding header
+func negotiate(header string, encodings []*ContentEncoding) *ContentEncoding {
+	accepted := acceptEncoding(header)
+
+	var (
+		best    *ContentEncoding
+		quality float64
+	)
+
+	for _, encoding := range encodings {
+		q, ok := accepted[strings.ToLower(encoding.Name)]
+		if !ok {
+			q = accepted["*"]
+		}
+
+		if q > quality {
+			best = encoding
+			quality = q
+		}
+	}
+
+	return best
+}
+
+func acceptEncoding(header string) map[string]float64 {
+	accepted := map[string]float64{}
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				q = value
+			}
+		}
+
+		accepted[name] = q
+	}
+
+	return accepted
+}
@@ -19,5 +19,9 @@ func main() {
 		panic(err)
 	}
 
-	http.ListenAndServe(":8080", http.FileServer(parcello.ManagerAt("/website")))
+	handler := &parcello.Handler{
+		FileSystem: parcello.ManagerAt("/website"),
+	}
+
+	http.ListenAndServe(":8080", handler)
 }
@@ -2,6 +2,7 @@
 package fake
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -57,6 +58,25 @@ type FileSystemManager struct {
 	addReturns struct {
 		result1 error
 	}
+	AssetPathStub        func(name string) (string, error)
+	assetPathMutex       sync.RWMutex
+	assetPathArgsForCall []struct {
+		name string
+	}
+	assetPathReturns struct {
+		result1 string
+		result2 error
+	}
+	WatchStub        func(ctx context.Context, pattern string) (<-chan parcello.Event, error)
+	watchMutex       sync.RWMutex
+	watchArgsForCall []struct {
+		ctx     context.Context
+		pattern string
+	}
+	watchReturns struct {
+		result1 <-chan parcello.Event
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -227,6 +247,73 @@ func (fake *FileSystemManager) AddReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FileSystemManager) AssetPath(name string) (string, error) {
+	fake.assetPathMutex.Lock()
+	fake.assetPathArgsForCall = append(fake.assetPathArgsForCall, struct {
+		name string
+	}{name})
+	fake.recordInvocation("AssetPath", []interface{}{name})
+	fake.assetPathMutex.Unlock()
+	if fake.AssetPathStub != nil {
+		return fake.AssetPathStub(name)
+	}
+	return fake.assetPathReturns.result1, fake.assetPathReturns.result2
+}
+
+func (fake *FileSystemManager) AssetPathCallCount() int {
+	fake.assetPathMutex.RLock()
+	defer fake.assetPathMutex.RUnlock()
+	return len(fake.assetPathArgsForCall)
+}
+
+func (fake *FileSystemManager) AssetPathArgsForCall(i int) string {
+	fake.assetPathMutex.RLock()
+	defer fake.assetPathMutex.RUnlock()
+	return fake.assetPathArgsForCall[i].name
+}
+
+func (fake *FileSystemManager) AssetPathReturns(result1 string, result2 error) {
+	fake.AssetPathStub = nil
+	fake.assetPathReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FileSystemManager) Watch(ctx context.Context, pattern string) (<-chan parcello.Event, error) {
+	fake.watchMutex.Lock()
+	fake.watchArgsForCall = append(fake.watchArgsForCall, struct {
+		ctx     context.Context
+		pattern string
+	}{ctx, pattern})
+	fake.recordInvocation("Watch", []interface{}{ctx, pattern})
+	fake.watchMutex.Unlock()
+	if fake.WatchStub != nil {
+		return fake.WatchStub(ctx, pattern)
+	}
+	return fake.watchReturns.result1, fake.watchReturns.result2
+}
+
+func (fake *FileSystemManager) WatchCallCount() int {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return len(fake.watchArgsForCall)
+}
+
+func (fake *FileSystemManager) WatchArgsForCall(i int) (context.Context, string) {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return fake.watchArgsForCall[i].ctx, fake.watchArgsForCall[i].pattern
+}
+
+func (fake *FileSystemManager) WatchReturns(result1 <-chan parcello.Event, result2 error) {
+	fake.WatchStub = nil
+	fake.watchReturns = struct {
+		result1 <-chan parcello.Event
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FileSystemManager) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -240,6 +327,10 @@ func (fake *FileSystemManager) Invocations() map[string][][]interface{} {
 	defer fake.dirMutex.RUnlock()
 	fake.addMutex.RLock()
 	defer fake.addMutex.RUnlock()
+	fake.assetPathMutex.RLock()
+	defer fake.assetPathMutex.RUnlock()
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -0,0 +1,118 @@
+package parcello
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// fingerprintLen is the number of hash characters in the fingerprinted names
+const fingerprintLen = 8
+
+// hashInfo is implemented by the file infos that know the hash of the content
+type hashInfo interface {
+	Hash() string
+}
+
+// Fingerprint returns the fingerprinted path of the named resource. The
+// fingerprint is a prefix of the SHA-256 hash of the content, which is added
+// before the extension (for instance app.js becomes app.3f9a1c2b.js).
+func Fingerprint(fileSystem FileSystem, name string) (string, error) {
+	hash, err := contentHash(fileSystem, name)
+	if err != nil {
+		return "", err
+	}
+
+	return fingerprint(name, hash), nil
+}
+
+func fingerprint(name, hash string) string {
+	if len(hash) > fingerprintLen {
+		hash = hash[:fingerprintLen]
+	}
+
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+
+	return dir + strings.TrimSuffix(base, ext) + "." + hash + ext
+}
+
+// unfingerprint returns the original name and the fingerprint of given
+// fingerprinted name
+func unfingerprint(name string) (string, string, bool) {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	base = strings.TrimSuffix(base, ext)
+
+	index := strings.LastIndex(base, ".")
+	if index <= 0 || ext == "" {
+		return name, "", false
+	}
+
+	hash := base[index+1:]
+	if len(hash) < 6 {
+		return name, "", false
+	}
+
+	for _, char := range hash {
+		if !strings.ContainsRune("0123456789abcdef", char) {
+			return name, "", false
+		}
+	}
+
+	return dir + base[:index] + ext, hash, true
+}
+
+// contentHash returns the SHA-256 hash of the named resource. The hash is
+// read from the bundle if it is available, otherwise it is computed.
+func contentHash(fileSystem FileSystem, name string) (string, error) {
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	return fileHash(name, file)
+}
+
+// fileHash returns the SHA-256 hash of the file. The read offset is restored
+// to the beginning of the file when the hash has to be computed.
+func fileHash(name string, file ReadOnlyFile) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		return "", &os.PathError{Op: "hash", Path: name, Err: ErrIsDirectory}
+	}
+
+	if info, ok := info.(hashInfo); ok && info.Hash() != "" {
+		return info.Hash(), nil
+	}
+
+	checksum, err := hash(file)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return checksum, nil
+}
+
+func hash(reader io.Reader) (string, error) {
+	digest := sha256.New()
+
+	if _, err := io.Copy(digest, reader); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
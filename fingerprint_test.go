@@ -0,0 +1,88 @@
+package parcello_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Fingerprint", func() {
+	var fileSystem parcello.FileSystem
+
+	BeforeEach(func() {
+		fileSystem = parcello.FromFS(fstest.MapFS{
+			"assets/app.js": &fstest.MapFile{Data: []byte("console.log('hello');")},
+			"LICENSE":       &fstest.MapFile{Data: []byte("MIT")},
+		})
+	})
+
+	It("adds the content hash to the name", func() {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte("console.log('hello');")))
+
+		name, err := parcello.Fingerprint(fileSystem, "/assets/app.js")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("/assets/app." + hash[:8] + ".js"))
+	})
+
+	Context("when the resource does not have extension", func() {
+		It("appends the content hash", func() {
+			hash := fmt.Sprintf("%x", sha256.Sum256([]byte("MIT")))
+
+			name, err := parcello.Fingerprint(fileSystem, "/LICENSE")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("/LICENSE." + hash[:8]))
+		})
+	})
+
+	Context("when the resource is bundled", func() {
+		It("uses the recorded hash", func() {
+			compressor := &parcello.ZipCompressor{
+				Config: &parcello.CompressorConfig{
+					Logger:   GinkgoWriter,
+					Filename: "bundle",
+					Recurive: true,
+				},
+			}
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: fileSystem,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			manager := &parcello.ResourceManager{}
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			file, err := manager.Open("/assets/app.js")
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := file.Stat()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			hash := fmt.Sprintf("%x", sha256.Sum256([]byte("console.log('hello');")))
+			Expect(info.(*parcello.ResourceFileInfo).Hash()).To(Equal(hash))
+
+			name, err := parcello.Fingerprint(manager, "/assets/app.js")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("/assets/app." + hash[:8] + ".js"))
+		})
+	})
+
+	Context("when the resource is a directory", func() {
+		It("returns an error", func() {
+			_, err := parcello.Fingerprint(fileSystem, "/assets")
+			Expect(err).To(MatchError("hash /assets: Is directory"))
+		})
+	})
+
+	Context("when the resource does not exist", func() {
+		It("returns an error", func() {
+			_, err := parcello.Fingerprint(fileSystem, "/app.css")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,366 @@
+package parcello
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// Make sure the FileSystem adapter implements the io/fs interfaces
+	_ fs.ReadDirFS  = &ioFS{}
+	_ fs.StatFS     = &ioFS{}
+	_ fs.ReadFileFS = &ioFS{}
+	_ fs.SubFS      = &ioFS{}
+	// Make sure the fs.FS adapter implements the FileSystemManager interface
+	_ FileSystemManager = FromFS(nil)
+	// Make sure the readDirFile implements the http.File interface
+	_ http.File = &readDirFile{}
+)
+
+// statFS is implemented by the file systems that can stat a file natively
+type statFS interface {
+	Stat(name string) (os.FileInfo, error)
+}
+
+// readDirFS is implemented by the file systems that can read a directory natively
+type readDirFS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// readFileFS is implemented by the file systems that can read a file natively
+type readFileFS interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// ToFS returns an fs.FS that provides access to the given FileSystem. The
+// returned file system implements fs.ReadDirFS, fs.StatFS, fs.ReadFileFS and
+// fs.SubFS, so it can be used with template.ParseFS, http.FS and fs.WalkDir.
+func ToFS(fileSystem FileSystem) fs.FS {
+	return &ioFS{fileSystem: fileSystem}
+}
+
+type ioFS struct {
+	fileSystem FileSystem
+}
+
+// Open opens the named file
+func (f *ioFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	file, err := f.fileSystem.Open(rootPath(name))
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+
+	if _, ok := file.(fs.ReadDirFile); ok {
+		return file, nil
+	}
+
+	return &readDirFile{ReadOnlyFile: file}, nil
+}
+
+// Stat returns a FileInfo describing the file
+func (f *ioFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fileSystem, ok := f.fileSystem.(statFS); ok {
+		info, err := fileSystem.Stat(rootPath(name))
+		if err != nil {
+			return nil, pathError("stat", name, err)
+		}
+
+		return info, nil
+	}
+
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	return file.Stat()
+}
+
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename
+func (f *ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fileSystem, ok := f.fileSystem.(readDirFS); ok {
+		entries, err := fileSystem.ReadDir(rootPath(name))
+		if err != nil {
+			return nil, pathError("readdir", name, err)
+		}
+
+		return entries, nil
+	}
+
+	return fs.ReadDir(onlyFS{f}, name)
+}
+
+// ReadFile reads the named file and returns its contents
+func (f *ioFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if fileSystem, ok := f.fileSystem.(readFileFS); ok {
+		data, err := fileSystem.ReadFile(rootPath(name))
+		if err != nil {
+			return nil, pathError("read", name, err)
+		}
+
+		return data, nil
+	}
+
+	return fs.ReadFile(onlyFS{f}, name)
+}
+
+// Sub returns an FS corresponding to the subtree rooted at dir
+func (f *ioFS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+
+	if dir == "." {
+		return f, nil
+	}
+
+	if manager, ok := f.fileSystem.(FileSystemManager); ok {
+		sub, err := manager.Dir(rootPath(dir))
+		if err != nil {
+			return nil, pathError("sub", dir, err)
+		}
+
+		return ToFS(sub), nil
+	}
+
+	return fs.Sub(onlyFS{f}, dir)
+}
+
+// onlyFS hides the optional interfaces of the wrapped fs.FS in order to
+// force the io/fs helpers to fallback to Open
+type onlyFS struct {
+	fs.FS
+}
+
+// readDirFile implements fs.ReadDirFile for the files that provide only Readdir
+type readDirFile struct {
+	ReadOnlyFile
+}
+
+// ReadDir reads the contents of the directory as a list of directory entries
+func (f *readDirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	infos, err := f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return dirEntries(infos), nil
+}
+
+// FromFS returns a FileSystemManager that provides access to the given fs.FS
+// (for instance embed.FS). The returned file system is read-only.
+func FromFS(fileSystem fs.FS) *FS {
+	return &FS{fileSystem: fileSystem}
+}
+
+// FS implements FileSystemManager on top of fs.FS
+type FS struct {
+	fileSystem fs.FS
+}
+
+// Open opens the named file for reading
+func (f *FS) Open(name string) (ReadOnlyFile, error) {
+	return f.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile is the generalized open call; most users will use Open
+func (f *FS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if isWritable(flag) || hasFlag(os.O_CREATE, flag) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+
+	file, err := f.fileSystem.Open(fsPath(name))
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+
+	return &fsFile{File: file}, nil
+}
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root.
+func (f *FS) Walk(dir string, fn filepath.WalkFunc) error {
+	return fs.WalkDir(f.fileSystem, fsPath(dir), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fn(path, nil, err)
+		}
+
+		info, err := entry.Info()
+		return fn(path, info, err)
+	})
+}
+
+// Dir returns a sub-manager for given path
+func (f *FS) Dir(name string) (FileSystemManager, error) {
+	fileSystem, err := fs.Sub(f.fileSystem, fsPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return FromFS(fileSystem), nil
+}
+
+// Add adds resource bundle to the file system. (noop)
+func (f *FS) Add(resource *Resource) error {
+	return nil
+}
+
+// AssetPath returns the fingerprinted path of the named resource
+func (f *FS) AssetPath(name string) (string, error) {
+	return Fingerprint(f, name)
+}
+
+// Stat returns a FileInfo describing the file
+func (f *FS) Stat(name string) (os.FileInfo, error) {
+	return fs.Stat(f.fileSystem, fsPath(name))
+}
+
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fileSystem, fsPath(name))
+}
+
+// ReadFile reads the named file and returns its contents
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fileSystem, fsPath(name))
+}
+
+var _ File = &fsFile{}
+
+// fsFile implements File on top of fs.File
+type fsFile struct {
+	fs.File
+}
+
+// Seek sets the offset for the next Read
+func (f *fsFile) Seek(offset int64, whence int) (int64, error) {
+	if seeker, ok := f.File.(io.Seeker); ok {
+		return seeker.Seek(offset, whence)
+	}
+
+	return 0, f.unsupported("seek")
+}
+
+// ReadAt reads len(p) bytes starting at byte offset off
+func (f *fsFile) ReadAt(p []byte, off int64) (int, error) {
+	if reader, ok := f.File.(io.ReaderAt); ok {
+		return reader.ReadAt(p, off)
+	}
+
+	return 0, f.unsupported("read")
+}
+
+// Write is disabled and returns ErrReadOnly
+func (f *fsFile) Write(p []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+// Readdir reads the contents of the directory associated with file and
+// returns a slice of up to n FileInfo values
+func (f *fsFile) Readdir(n int) ([]os.FileInfo, error) {
+	dir, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, f.unsupported("readdir")
+	}
+
+	entries, err := dir.ReadDir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []os.FileInfo{}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return infos, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// ReadDir reads the contents of the directory as a list of directory entries
+func (f *fsFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if dir, ok := f.File.(fs.ReadDirFile); ok {
+		return dir.ReadDir(n)
+	}
+
+	return nil, f.unsupported("readdir")
+}
+
+func (f *fsFile) unsupported(op string) error {
+	name := ""
+
+	if info, err := f.Stat(); err == nil {
+		name = info.Name()
+	}
+
+	return &os.PathError{Op: op, Path: name, Err: ErrNotSupported}
+}
+
+func dirEntries(infos []os.FileInfo) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(infos))
+
+	for index, info := range infos {
+		entries[index] = fs.FileInfoToDirEntry(info)
+	}
+
+	return entries
+}
+
+// rootPath converts a slash-separated io/fs path to a parcello path
+func rootPath(name string) string {
+	if name == "." {
+		return "/"
+	}
+
+	return filepath.FromSlash(name)
+}
+
+// fsPath converts a parcello path to a slash-separated io/fs path
+func fsPath(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	name = strings.TrimPrefix(name, "/")
+
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+// pathError reports the error for the io/fs name rather than the internal one
+func pathError(op, name string, err error) error {
+	if perr, ok := err.(*os.PathError); ok {
+		return &fs.PathError{Op: op, Path: name, Err: perr.Err}
+	}
+
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
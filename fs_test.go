@@ -0,0 +1,196 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"html/template"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("FS", func() {
+	var manager *parcello.ResourceManager
+
+	BeforeEach(func() {
+		compressor := parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   ioutil.Discard,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.Dir("./fixture"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		manager = &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+	})
+
+	Describe("ToFS", func() {
+		It("implements the io/fs interfaces for resource manager", func() {
+			fileSystem := parcello.ToFS(manager)
+			Expect(fstest.TestFS(fileSystem,
+				"resource/reports/2018.txt",
+				"resource/scripts/schema.sql",
+				"resource/templates/html/index.html",
+				"resource/templates/yml/schema.yml",
+			)).To(Succeed())
+		})
+
+		It("implements the io/fs interfaces for dir", func() {
+			fileSystem := parcello.ToFS(parcello.Dir("./fixture"))
+			Expect(fstest.TestFS(fileSystem, "resource/reports/2018.txt")).To(Succeed())
+		})
+
+		It("walks the hierarchy", func() {
+			paths := []string{}
+
+			err := fs.WalkDir(parcello.ToFS(manager), "resource/templates", func(path string, entry fs.DirEntry, err error) error {
+				paths = append(paths, path)
+				return err
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{
+				"resource/templates",
+				"resource/templates/html",
+				"resource/templates/html/index.html",
+				"resource/templates/yml",
+				"resource/templates/yml/schema.yml",
+			}))
+		})
+
+		It("reads a file", func() {
+			data, err := fs.ReadFile(parcello.ToFS(manager), "resource/reports/2018.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("Report 2018\n"))
+		})
+
+		It("parses the templates", func() {
+			tmpl, err := template.ParseFS(parcello.ToFS(manager), "resource/templates/html/*.html")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tmpl.Lookup("index.html")).NotTo(BeNil())
+		})
+
+		It("serves the files over http", func() {
+			handler := http.FileServer(http.FS(parcello.ToFS(manager)))
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest("GET", "/resource/reports/2018.txt", nil)
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("Report 2018\n"))
+		})
+
+		Context("when the path is invalid", func() {
+			It("returns an error", func() {
+				_, err := parcello.ToFS(manager).Open("/resource")
+				Expect(err).To(MatchError(fs.ErrInvalid))
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			It("returns an error", func() {
+				_, err := fs.Stat(parcello.ToFS(manager), "resource/migration.sql")
+				Expect(err).To(MatchError(fs.ErrNotExist))
+				Expect(err).To(MatchError("stat resource/migration.sql: file does not exist"))
+			})
+		})
+	})
+
+	Describe("FromFS", func() {
+		var fileSystem *parcello.FS
+
+		BeforeEach(func() {
+			fileSystem = parcello.FromFS(fstest.MapFS{
+				"public/index.html":    &fstest.MapFile{Data: []byte("<html></html>")},
+				"public/css/main.css":  &fstest.MapFile{Data: []byte("body {}")},
+				"database/schema.sql":  &fstest.MapFile{Data: []byte("CREATE TABLE users;")},
+				"database/command.sql": &fstest.MapFile{Data: []byte("SELECT 1;")},
+			})
+		})
+
+		It("opens a file successfully", func() {
+			file, err := fileSystem.Open("/database/schema.sql")
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := ioutil.ReadAll(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("CREATE TABLE users;"))
+		})
+
+		It("reads a directory successfully", func() {
+			file, err := fileSystem.Open("database")
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := file.Readdir(-1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info).To(HaveLen(2))
+			Expect(info[0].Name()).To(Equal("command.sql"))
+			Expect(info[1].Name()).To(Equal("schema.sql"))
+		})
+
+		It("returns a sub-manager", func() {
+			dir, err := fileSystem.Dir("/public")
+			Expect(err).NotTo(HaveOccurred())
+
+			file, err := dir.Open("css/main.css")
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := ioutil.ReadAll(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("body {}"))
+		})
+
+		It("can be compressed", func() {
+			compressor := parcello.ZipCompressor{
+				Config: &parcello.CompressorConfig{
+					Logger:   ioutil.Discard,
+					Filename: "bundle",
+					Recurive: true,
+				},
+			}
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: fileSystem,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundle.Count).To(Equal(4))
+
+			reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reader.File[0].Name).To(Equal("database/command.sql"))
+			Expect(reader.File[1].Name).To(Equal("database/schema.sql"))
+			Expect(reader.File[2].Name).To(Equal("public/css/main.css"))
+			Expect(reader.File[3].Name).To(Equal("public/index.html"))
+		})
+
+		Context("when the file is open for write", func() {
+			It("returns an error", func() {
+				file, err := fileSystem.OpenFile("/database/schema.sql", os.O_RDWR, 0600)
+				Expect(file).To(BeNil())
+				Expect(err).To(MatchError("open /database/schema.sql: File is read-only"))
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			It("returns an error", func() {
+				file, err := fileSystem.Open("/database/migration.sql")
+				Expect(file).To(BeNil())
+				Expect(err).To(MatchError(os.ErrNotExist))
+			})
+		})
+	})
+})
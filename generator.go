@@ -3,21 +3,50 @@ package parcello
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"go/format"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var _ Composer = &Generator{}
 
+// fingerprintPrefix starts the header line that records the fingerprint of
+// the generated source code
+const fingerprintPrefix = "// parcello:fingerprint "
+
+// embedInvalidChars are the characters that the go command does not allow in
+// the names of the embedded files
+const embedInvalidChars = "\"'*<>?`|:;\\"
+
+// embedEscaper escapes the meta characters of the go:embed patterns
+var embedEscaper = strings.NewReplacer("[", "\\[", "]", "\\]")
+
 // GeneratorConfig controls how the code generation happens
 type GeneratorConfig struct {
 	// Package determines the name of the package
 	Package string
 	// InlcudeDocs determines whether to include documentation
 	InlcudeDocs bool
+	// EmbedFS determines whether to embed the resources with go:embed
+	// directive instead of compressing them into a byte slice literal
+	EmbedFS bool
+	// ResourceDir is the path of the resource directory relative to the
+	// package directory (used only when EmbedFS is enabled)
+	ResourceDir string
+	// Namespace registers the resources under given namespace (usually the
+	// import path of the package). The resources are not namespaced if it
+	// is empty.
+	Namespace string
 }
 
 // Generator generates an embedable resource
@@ -34,24 +63,194 @@ func (g *Generator) Compose(bundle *Bundle) error {
 
 	if g.Config.InlcudeDocs {
 		fmt.Fprintln(template, "// Code generated by parcello; DO NOT EDIT.")
+	}
+
+	if bundle.Fingerprint != "" {
+		fingerprint, err := g.fingerprint(bundle)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(template, fingerprintPrefix+fingerprint)
+	}
+
+	if g.Config.InlcudeDocs {
 		fmt.Fprintln(template, "")
 		fmt.Fprintln(template, "// Package", g.Config.Package, "contains embedded resources")
+	} else if bundle.Fingerprint != "" {
+		fmt.Fprintln(template, "")
 	}
 
 	fmt.Fprintln(template, "package", g.Config.Package)
 	fmt.Fprintln(template)
+
+	if g.Config.EmbedFS {
+		if err := g.embed(template, bundle); err != nil {
+			return err
+		}
+	} else {
+		g.compose(template, bundle)
+	}
+
+	return g.write(bundle.Name, template.Bytes())
+}
+
+// UpToDate returns true if the source code of the bundle has been generated
+// from the same resources with the same configuration
+func (g *Generator) UpToDate(bundle *Bundle) (bool, error) {
+	if bundle.Fingerprint == "" {
+		return false, nil
+	}
+
+	expected, err := g.fingerprint(bundle)
+	if err != nil {
+		return false, err
+	}
+
+	actual, err := g.read(bundle.Name)
+	if err != nil {
+		return false, err
+	}
+
+	return actual == expected, nil
+}
+
+// fingerprint returns the fingerprint of the bundle combined with the
+// configuration of the generator
+func (g *Generator) fingerprint(bundle *Bundle) (string, error) {
+	data, err := json.Marshal(g.Config)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintln(hash, bundle.Fingerprint)
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// read returns the fingerprint recorded in the header of the generated
+// source code. It does not read past the package clause.
+func (g *Generator) read(name string) (string, error) {
+	filename := fmt.Sprintf("%s.go", name)
+
+	file, err := g.FileSystem.OpenFile(filename, os.O_RDONLY, 0)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, fingerprintPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, fingerprintPrefix)), nil
+		}
+
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+
+	return "", scanner.Err()
+}
+
+func (g *Generator) compose(template io.Writer, bundle *Bundle) {
 	fmt.Fprintf(template, "import \"github.com/phogolabs/parcello\"")
 	fmt.Fprintln(template)
 	fmt.Fprintln(template)
 	fmt.Fprintln(template, "func init() {")
-	fmt.Fprintln(template, "\tparcello.AddResource([]byte{")
+	if g.Config.Namespace != "" {
+		fmt.Fprintf(template, "\tparcello.AddNamespaceResource(%q, []byte{\n", g.Config.Namespace)
+	} else {
+		fmt.Fprintln(template, "\tparcello.AddResource([]byte{")
+	}
 
 	template.Write(g.prepare(bundle.Body))
 
 	fmt.Fprintln(template, "\t})")
 	fmt.Fprintln(template, "}")
+}
 
-	return g.write(bundle.Name, template.Bytes())
+func (g *Generator) embed(template io.Writer, bundle *Bundle) error {
+	root := filepath.ToSlash(filepath.Clean(g.Config.ResourceDir))
+
+	fmt.Fprintln(template, "import (")
+	fmt.Fprintln(template, "\t\"embed\"")
+
+	if root != "." {
+		fmt.Fprintln(template, "\t\"io/fs\"")
+	}
+
+	fmt.Fprintln(template)
+	fmt.Fprintln(template, "\t\"github.com/phogolabs/parcello\"")
+	fmt.Fprintln(template, ")")
+	fmt.Fprintln(template)
+
+	for _, name := range bundle.Files {
+		pattern, err := embedPattern(path.Join(root, filepath.ToSlash(name)))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(template, "//go:embed", pattern)
+	}
+
+	fmt.Fprintln(template, "var resourceFS embed.FS")
+	fmt.Fprintln(template)
+	fmt.Fprintln(template, "func init() {")
+
+	if root == "." {
+		g.addFS(template, "resourceFS")
+	} else {
+		fmt.Fprintf(template, "\tfileSystem, err := fs.Sub(resourceFS, %q)\n", root)
+		fmt.Fprintln(template, "\tif err != nil {")
+		fmt.Fprintln(template, "\t\tpanic(err)")
+		fmt.Fprintln(template, "\t}")
+		fmt.Fprintln(template)
+		g.addFS(template, "fileSystem")
+	}
+
+	fmt.Fprintln(template, "}")
+	return nil
+}
+
+// embedPattern returns a go:embed pattern that matches exactly the file with
+// given name. The glob meta characters are escaped and the pattern is quoted
+// if the name contains spaces. The names that the go command refuses to
+// embed are reported as an error instead of being dropped silently.
+func embedPattern(name string) (string, error) {
+	for _, char := range name {
+		if char < ' ' || strings.ContainsRune(embedInvalidChars, char) ||
+			(char >= utf8.RuneSelf && !unicode.IsLetter(char)) {
+			return "", fmt.Errorf("The file '%s' cannot be embedded: invalid character %q", name, char)
+		}
+	}
+
+	pattern := embedEscaper.Replace(name)
+
+	if strings.ContainsRune(pattern, ' ') {
+		pattern = strconv.Quote(pattern)
+	}
+
+	return pattern, nil
+}
+
+func (g *Generator) addFS(template io.Writer, name string) {
+	if g.Config.Namespace != "" {
+		fmt.Fprintf(template, "\tparcello.AddNamespaceFS(%q, %s)\n", g.Config.Namespace, name)
+		return
+	}
+
+	fmt.Fprintf(template, "\tparcello.AddFS(%s)\n", name)
 }
 
 func (g *Generator) prepare(data []byte) []byte {
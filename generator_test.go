@@ -127,6 +127,207 @@ var _ = Describe("Generator", func() {
 		})
 	})
 
+	Context("when the namespace is provided", func() {
+		BeforeEach(func() {
+			generator.Config.Namespace = "github.com/phogolabs/example/database"
+		})
+
+		It("registers the resource under the namespace", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+
+			_, err := buffer.Seek(0, io.SeekStart)
+			Expect(err).To(BeNil())
+			content, err := ioutil.ReadAll(buffer)
+			Expect(err).To(BeNil())
+
+			Expect(content).To(ContainSubstring("parcello.AddNamespaceResource(\"github.com/phogolabs/example/database\", []byte{"))
+			Expect(content).NotTo(ContainSubstring("parcello.AddResource"))
+		})
+
+		Context("when embed.FS is enabled", func() {
+			BeforeEach(func() {
+				generator.Config.EmbedFS = true
+				bundle.Files = []string{"reports/2018.txt"}
+			})
+
+			It("registers the file system under the namespace", func() {
+				Expect(generator.Compose(bundle)).To(Succeed())
+
+				_, err := buffer.Seek(0, io.SeekStart)
+				Expect(err).To(BeNil())
+				content, err := ioutil.ReadAll(buffer)
+				Expect(err).To(BeNil())
+
+				Expect(content).To(ContainSubstring("parcello.AddNamespaceFS(\"github.com/phogolabs/example/database\", resourceFS)"))
+				Expect(content).NotTo(ContainSubstring("parcello.AddFS"))
+			})
+		})
+	})
+
+	Context("when embed.FS is enabled", func() {
+		BeforeEach(func() {
+			generator.Config.EmbedFS = true
+			bundle.Files = []string{"reports/2018.txt", "templates/index page.html"}
+		})
+
+		It("generates go:embed directives", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+
+			_, err := buffer.Seek(0, io.SeekStart)
+			Expect(err).To(BeNil())
+			content, err := ioutil.ReadAll(buffer)
+			Expect(err).To(BeNil())
+
+			Expect(content).To(ContainSubstring("package mypackage"))
+			Expect(content).To(ContainSubstring("//go:embed reports/2018.txt\n"))
+			Expect(content).To(ContainSubstring("//go:embed \"templates/index page.html\"\n"))
+			Expect(content).To(ContainSubstring("var resourceFS embed.FS"))
+			Expect(content).To(ContainSubstring("parcello.AddFS(resourceFS)"))
+			Expect(content).NotTo(ContainSubstring("parcello.AddResource"))
+			Expect(content).NotTo(ContainSubstring("io/fs"))
+		})
+
+		Context("when the file names contain special characters", func() {
+			BeforeEach(func() {
+				bundle.Files = []string{"assets/[draft] notes.txt", "assets/v[1].css"}
+			})
+
+			It("escapes and quotes the go:embed patterns", func() {
+				Expect(generator.Compose(bundle)).To(Succeed())
+
+				_, err := buffer.Seek(0, io.SeekStart)
+				Expect(err).To(BeNil())
+				content, err := ioutil.ReadAll(buffer)
+				Expect(err).To(BeNil())
+
+				Expect(content).To(ContainSubstring("//go:embed \"assets/\\\\[draft\\\\] notes.txt\"\n"))
+				Expect(content).To(ContainSubstring("//go:embed assets/v\\[1\\].css\n"))
+			})
+		})
+
+		Context("when a file name cannot be embedded", func() {
+			BeforeEach(func() {
+				bundle.Files = []string{"assets/what?.txt"}
+			})
+
+			It("returns an error", func() {
+				Expect(generator.Compose(bundle)).To(MatchError("The file 'assets/what?.txt' cannot be embedded: invalid character '?'"))
+			})
+		})
+
+		Context("when the resource directory is a sub-directory", func() {
+			BeforeEach(func() {
+				generator.Config.ResourceDir = "public"
+			})
+
+			It("generates go:embed directives for the sub-directory", func() {
+				Expect(generator.Compose(bundle)).To(Succeed())
+
+				_, err := buffer.Seek(0, io.SeekStart)
+				Expect(err).To(BeNil())
+				content, err := ioutil.ReadAll(buffer)
+				Expect(err).To(BeNil())
+
+				Expect(content).To(ContainSubstring("//go:embed public/reports/2018.txt\n"))
+				Expect(content).To(ContainSubstring("fs.Sub(resourceFS, \"public\")"))
+				Expect(content).To(ContainSubstring("parcello.AddFS(fileSystem)"))
+			})
+		})
+	})
+
+	Context("when the bundle has a fingerprint", func() {
+		BeforeEach(func() {
+			bundle.Fingerprint = "e3b0c44298fc1c149afbf4c8996fb924"
+		})
+
+		It("records the fingerprint in the header", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+
+			_, err := buffer.Seek(0, io.SeekStart)
+			Expect(err).To(BeNil())
+			content, err := ioutil.ReadAll(buffer)
+			Expect(err).To(BeNil())
+
+			Expect(string(content)).To(MatchRegexp("^// parcello:fingerprint [0-9a-f]{64}\n\npackage mypackage"))
+		})
+
+		Context("when include API documentation is enabled", func() {
+			BeforeEach(func() {
+				generator.Config.InlcudeDocs = true
+			})
+
+			It("records the fingerprint after the generated code notice", func() {
+				Expect(generator.Compose(bundle)).To(Succeed())
+
+				_, err := buffer.Seek(0, io.SeekStart)
+				Expect(err).To(BeNil())
+				content, err := ioutil.ReadAll(buffer)
+				Expect(err).To(BeNil())
+
+				Expect(string(content)).To(MatchRegexp("^// Code generated by parcello; DO NOT EDIT.\n// parcello:fingerprint [0-9a-f]{64}\n\n// Package mypackage"))
+			})
+		})
+	})
+
+	Describe("UpToDate", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "parcello")
+			Expect(err).To(BeNil())
+
+			generator.FileSystem = parcello.Dir(dir)
+			bundle.Fingerprint = "e3b0c44298fc1c149afbf4c8996fb924"
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("returns true when the bundle has been generated", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+			Expect(generator.UpToDate(bundle)).To(BeTrue())
+		})
+
+		It("returns false when the source code does not exist", func() {
+			Expect(generator.UpToDate(bundle)).To(BeFalse())
+		})
+
+		It("returns false when the fingerprint changes", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+
+			bundle.Fingerprint = "d41d8cd98f00b204e9800998ecf8427e"
+			Expect(generator.UpToDate(bundle)).To(BeFalse())
+		})
+
+		It("returns false when the configuration changes", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+
+			generator.Config.Namespace = "github.com/phogolabs/example/database"
+			Expect(generator.UpToDate(bundle)).To(BeFalse())
+		})
+
+		It("returns false when the bundle has no fingerprint", func() {
+			Expect(generator.Compose(bundle)).To(Succeed())
+
+			bundle.Fingerprint = ""
+			Expect(generator.UpToDate(bundle)).To(BeFalse())
+		})
+
+		Context("when the file system fails", func() {
+			It("returns the error", func() {
+				fileSystem.OpenFileReturns(nil, fmt.Errorf("Oh no!"))
+				generator.FileSystem = fileSystem
+
+				upToDate, err := generator.UpToDate(bundle)
+				Expect(err).To(MatchError("Oh no!"))
+				Expect(upToDate).To(BeFalse())
+			})
+		})
+	})
+
 	Context("when the package name is not provided", func() {
 		BeforeEach(func() {
 			generator.Config.Package = ""
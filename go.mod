@@ -1,13 +1,27 @@
 module github.com/phogolabs/parcello
 
-go 1.13
+go 1.17
 
 require (
+	github.com/andybalholm/brotli v1.0.5
 	github.com/blang/vfs v1.0.0
 	github.com/daaku/go.zipexe v1.0.1
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0
+	github.com/klauspost/compress v1.15.15
 	github.com/mattn/go-sqlite3 v1.14.4
 	github.com/onsi/ginkgo v1.14.0
 	github.com/onsi/gomega v1.10.1
 	github.com/phogolabs/cli v0.0.0-20191212161310-ce689d871370
+	github.com/ulikunitz/xz v0.5.11
+	gopkg.in/yaml.v2 v2.3.0
+)
+
+require (
+	github.com/nxadm/tail v1.4.4 // indirect
+	golang.org/x/net v0.0.0-20200520004742-59133d7f0dd7 // indirect
+	golang.org/x/sys v0.0.0-20200519105757-fe76b779f299 // indirect
+	golang.org/x/text v0.3.2 // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 )
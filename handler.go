@@ -0,0 +1,415 @@
+package parcello
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ http.Handler = &Handler{}
+
+// immutable is the cache control of the fingerprinted resources
+const immutable = "public, max-age=31536000, immutable"
+
+// HandlerConfig controls how the resources are served
+type HandlerConfig struct {
+	// Encodings are the names of the content encodings of the precompressed
+	// variants in order of preference (by default br, zstd and gzip)
+	Encodings []string
+	// Fingerprints enables serving of fingerprinted resources (for instance
+	// app.3f9a1c2b.js for app.js) with immutable cache control
+	Fingerprints bool
+	// Prefix is stripped from the path of the requests (for instance
+	// /static). The requests without the prefix are not found.
+	Prefix string
+	// SPA serves /index.html for the missing resources without extension, so
+	// the client-side routes of a single-page application can be reloaded
+	SPA bool
+	// DisableListing responds with not found instead of listing the
+	// directories that do not have index.html
+	DisableListing bool
+	// NotFound is the path of the page that is served with 404 status code
+	// (for instance /404.html)
+	NotFound string
+	// CacheControl provides the Cache-Control header of the resources that
+	// match given patterns (the first match wins)
+	CacheControl []CacheControl
+}
+
+// CacheControl associates the resources that match the pattern with a
+// Cache-Control header
+type CacheControl struct {
+	// Pattern is matched against the path and the name of the resource
+	Pattern string
+	// Value of the Cache-Control header (for instance no-cache)
+	Value string
+}
+
+// Handler serves the resources of a file system over HTTP. If the file
+// system contains precompressed variants of the requested file (for
+// instance app.js.br or app.js.gz), the handler negotiates the content
+// encoding with the client and serves the variant. Every response has an
+// ETag of the content hash, which is validated with If-None-Match. The range
+// requests are served by http.ServeContent.
+type Handler struct {
+	// FileSystem represents the underlying file system
+	FileSystem FileSystem
+	// Config controls how the resources are served
+	Config *HandlerConfig
+
+	mutex  sync.Mutex
+	hashes map[string]*hashEntry
+}
+
+// hashEntry is the content hash of a file that does not record it. It is
+// valid as long as the modification time and the size do not change.
+type hashEntry struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// ServeHTTP serves the requested resource
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r, ok := h.strip(r)
+	if !ok {
+		h.notFound(w, r)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+
+	encodings, err := h.encodings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	info, err := h.stat(name)
+
+	if err != nil && h.config().Fingerprints {
+		if original, originalInfo, ok := h.resolve(name); ok {
+			name, info, err = original, originalInfo, nil
+			w.Header().Set("Cache-Control", immutable)
+		}
+	}
+
+	if err == nil && info.IsDir() {
+		index := path.Join(name, "index.html")
+		indexInfo, indexErr := h.stat(index)
+		found := indexErr == nil && !indexInfo.IsDir()
+
+		switch {
+		case found && strings.HasSuffix(r.URL.Path, "/"):
+			name, info = index, indexInfo
+		case found || !h.config().DisableListing:
+			// redirects to the path with trailing slash or lists the directory
+			h.fallback(w, r)
+			return
+		default:
+			err = os.ErrNotExist
+		}
+	}
+
+	if err != nil && h.spa(r, name) {
+		name = "/index.html"
+		info, err = h.stat(name)
+	}
+
+	if err != nil || info.IsDir() {
+		h.notFound(w, r)
+		return
+	}
+
+	var encoding *ContentEncoding
+
+	if !isEncoded(name, encodings) {
+		if variants := h.variants(name, encodings); len(variants) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding = negotiate(r.Header.Get("Accept-Encoding"), variants)
+		}
+	}
+
+	if err := h.serve(w, r, name, info, encoding); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// strip returns the request without the prefix. It returns false if the path
+// of the request does not have the prefix.
+func (h *Handler) strip(r *http.Request) (*http.Request, bool) {
+	prefix := strings.TrimSuffix(h.config().Prefix, "/")
+	if prefix == "" {
+		return r, true
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch {
+	case len(name) == len(r.URL.Path):
+		return r, false
+	case name == "":
+		name = "/"
+	case !strings.HasPrefix(name, "/"):
+		return r, false
+	}
+
+	request := r.Clone(r.Context())
+	request.URL.Path = name
+	request.URL.RawPath = ""
+
+	return request, true
+}
+
+// spa returns true if the request of the missing resource should be served
+// by the index of the single-page application
+func (h *Handler) spa(r *http.Request, name string) bool {
+	if !h.config().SPA || path.Ext(name) != "" {
+		return false
+	}
+
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// notFound responds with the not found page
+func (h *Handler) notFound(w http.ResponseWriter, r *http.Request) {
+	name := h.config().NotFound
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	name = path.Clean("/" + name)
+
+	file, err := h.FileSystem.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType, err := h.contentType(name, info)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Del("Cache-Control")
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	w.WriteHeader(http.StatusNotFound)
+
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, file)
+	}
+}
+
+// resolve returns the original resource of a fingerprinted name. The
+// fingerprint must match the content hash of the resource.
+func (h *Handler) resolve(name string) (string, os.FileInfo, bool) {
+	original, fingerprint, ok := unfingerprint(name)
+	if !ok {
+		return name, nil, false
+	}
+
+	info, err := h.stat(original)
+	if err != nil || info.IsDir() {
+		return name, nil, false
+	}
+
+	checksum, err := h.contentHash(original)
+	if err != nil || !strings.HasPrefix(checksum, fingerprint) {
+		return name, nil, false
+	}
+
+	return original, info, true
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, name string, info os.FileInfo, encoding *ContentEncoding) error {
+	contentType, err := h.contentType(name, info)
+	if err != nil {
+		return err
+	}
+
+	target := name
+	if encoding != nil {
+		target = name + encoding.Extension
+	}
+
+	file, err := h.FileSystem.Open(target)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	checksum, err := h.fileHash(target, file)
+	if err != nil {
+		return err
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("ETag", strconv.Quote(checksum))
+
+	if encoding != nil {
+		variant, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		header.Set("Content-Encoding", encoding.Name)
+
+		// http.ServeContent does not set the length of encoded content
+		if r.Header.Get("Range") == "" {
+			header.Set("Content-Length", strconv.FormatInt(variant.Size(), 10))
+		}
+	}
+
+	if header.Get("Cache-Control") == "" {
+		if err := h.cacheControl(header, name); err != nil {
+			return err
+		}
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), file)
+	return nil
+}
+
+// contentHash returns the SHA-256 hash of the named resource
+func (h *Handler) contentHash(name string) (string, error) {
+	file, err := h.FileSystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	return h.fileHash(name, file)
+}
+
+// fileHash returns the SHA-256 hash of the file. The hash recorded in the
+// bundle is used if it is available. Otherwise the computed hash is cached
+// until the modification time or the size of the file changes, so the file
+// is not read on every request.
+func (h *Handler) fileHash(name string, file ReadOnlyFile) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if info, ok := info.(hashInfo); ok && info.Hash() != "" {
+		return info.Hash(), nil
+	}
+
+	h.mutex.Lock()
+	entry, ok := h.hashes[name]
+	h.mutex.Unlock()
+
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.hash, nil
+	}
+
+	checksum, err := fileHash(name, file)
+	if err != nil {
+		return "", err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.hashes == nil {
+		h.hashes = make(map[string]*hashEntry)
+	}
+
+	h.hashes[name] = &hashEntry{modTime: info.ModTime(), size: info.Size(), hash: checksum}
+	return checksum, nil
+}
+
+// cacheControl sets the Cache-Control header of the first matching rule
+func (h *Handler) cacheControl(header http.Header, name string) error {
+	name = strings.TrimPrefix(name, "/")
+
+	for _, rule := range h.config().CacheControl {
+		matched, err := match(rule.Pattern, name, path.Base(name))
+
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			header.Set("Cache-Control", rule.Value)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// contentType returns the content type of the original resource. The content
+// type stored in the bundle takes precedence over the detected one.
+func (h *Handler) contentType(name string, info os.FileInfo) (string, error) {
+	if contentType := MetadataOf(info).ContentType(); contentType != "" {
+		return contentType, nil
+	}
+
+	return detectContentType(h.FileSystem, name)
+}
+
+// variants returns the encodings of the available precompressed variants
+func (h *Handler) variants(name string, encodings []*ContentEncoding) []*ContentEncoding {
+	variants := []*ContentEncoding{}
+
+	for _, encoding := range encodings {
+		if info, err := h.stat(name + encoding.Extension); err == nil && !info.IsDir() {
+			variants = append(variants, encoding)
+		}
+	}
+
+	return variants
+}
+
+func (h *Handler) stat(name string) (os.FileInfo, error) {
+	if fileSystem, ok := h.FileSystem.(statFS); ok {
+		return fileSystem.Stat(name)
+	}
+
+	file, err := h.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	return file.Stat()
+}
+
+func (h *Handler) encodings() ([]*ContentEncoding, error) {
+	return lookupEncodings(h.config().Encodings)
+}
+
+func (h *Handler) config() *HandlerConfig {
+	if h.Config == nil {
+		return &HandlerConfig{}
+	}
+
+	return h.Config
+}
+
+func (h *Handler) fallback(w http.ResponseWriter, r *http.Request) {
+	http.FileServer(h.FileSystem).ServeHTTP(w, r)
+}
@@ -0,0 +1,485 @@
+package parcello_test
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing/fstest"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		handler  *parcello.Handler
+		request  *http.Request
+		recorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		compressor := &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   ioutil.Discard,
+				Filename: "bundle",
+				Recurive: true,
+				Precompression: &parcello.Precompression{
+					Patterns: []string{"*.js"},
+				},
+				ContentTypes: []parcello.ContentType{
+					{Pattern: "apple-app-site-association", MediaType: "application/json"},
+				},
+			},
+		}
+
+		fileSystem := parcello.FromFS(fstest.MapFS{
+			"public/app.js":     &fstest.MapFile{Data: []byte("console.log('hello');")},
+			"public/index.html": &fstest.MapFile{Data: []byte("<html></html>")},
+			"public/main.wasm":  &fstest.MapFile{Data: []byte("\x00asm\x01\x00\x00\x00")},
+			"public/.well-known/apple-app-site-association": &fstest.MapFile{Data: []byte(`{"applinks": {}}`)},
+		})
+
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: fileSystem,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		manager := &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+		root, err := manager.Dir("/public")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler = &parcello.Handler{
+			FileSystem: root,
+		}
+
+		request = httptest.NewRequest("GET", "/app.js", nil)
+		recorder = httptest.NewRecorder()
+	})
+
+	It("serves the content type stored in the bundle", func() {
+		request = httptest.NewRequest("GET", "/main.wasm", nil)
+		handler.ServeHTTP(recorder, request)
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/wasm"))
+	})
+
+	Context("when the content type is overridden", func() {
+		It("serves the overridden content type", func() {
+			request = httptest.NewRequest("GET", "/.well-known/apple-app-site-association", nil)
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		})
+	})
+
+	It("serves the brotli variant", func() {
+		request.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		handler.ServeHTTP(recorder, request)
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Encoding")).To(Equal("br"))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/javascript"))
+		Expect(recorder.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(recorder.Header().Get("Content-Length")).To(Equal(strconv.Itoa(recorder.Body.Len())))
+
+		data, err := ioutil.ReadAll(brotli.NewReader(recorder.Body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("console.log('hello');"))
+	})
+
+	Context("when the client accepts gzip only", func() {
+		It("serves the gzip variant", func() {
+			request.Header.Set("Accept-Encoding", "gzip, br;q=0")
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Encoding")).To(Equal("gzip"))
+
+			reader, err := gzip.NewReader(recorder.Body)
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := ioutil.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("console.log('hello');"))
+		})
+	})
+
+	Context("when the client prefers gzip", func() {
+		It("serves the gzip variant", func() {
+			request.Header.Set("Accept-Encoding", "br;q=0.5, gzip")
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		})
+	})
+
+	Context("when the encodings are configured", func() {
+		It("serves the configured variants only", func() {
+			handler.Config = &parcello.HandlerConfig{
+				Encodings: []string{"zstd"},
+			}
+
+			request.Header.Set("Accept-Encoding", "*")
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Header().Get("Content-Encoding")).To(Equal("zstd"))
+		})
+	})
+
+	Context("when the client does not accept any encoding", func() {
+		It("serves the original resource", func() {
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(recorder.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+			Expect(recorder.Body.String()).To(Equal("console.log('hello');"))
+		})
+	})
+
+	Context("when the resource does not have variants", func() {
+		It("serves the original resource", func() {
+			request = httptest.NewRequest("GET", "/index.html", nil)
+			request.Header.Set("Accept-Encoding", "gzip, br")
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("<html></html>"))
+
+			request = httptest.NewRequest("GET", "/", nil)
+			request.Header.Set("Accept-Encoding", "gzip, br")
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(recorder.Header().Get("Vary")).To(BeEmpty())
+			Expect(recorder.Body.String()).To(Equal("<html></html>"))
+		})
+	})
+
+	Context("when the resource is requested", func() {
+		It("sets the content hash as ETag", func() {
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("ETag")).To(Equal(etag("console.log('hello');")))
+			Expect(recorder.Header().Get("Cache-Control")).To(BeEmpty())
+		})
+
+		It("sets different ETags for the variants", func() {
+			request.Header.Set("Accept-Encoding", "br")
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Header().Get("ETag")).To(Equal(etag(recorder.Body.String())))
+			Expect(recorder.Header().Get("ETag")).NotTo(Equal(etag("console.log('hello');")))
+		})
+
+		Context("when the ETag matches If-None-Match", func() {
+			It("returns not modified", func() {
+				request.Header.Set("If-None-Match", etag("console.log('hello');"))
+				handler.ServeHTTP(recorder, request)
+
+				Expect(recorder.Code).To(Equal(http.StatusNotModified))
+				Expect(recorder.Body.Len()).To(BeZero())
+			})
+		})
+
+		Context("when the file system does not record hashes", func() {
+			It("computes the ETag", func() {
+				handler.FileSystem = parcello.FromFS(fstest.MapFS{
+					"app.js": &fstest.MapFile{Data: []byte("console.log('hello');")},
+				})
+
+				handler.ServeHTTP(recorder, request)
+
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Header().Get("ETag")).To(Equal(etag("console.log('hello');")))
+				Expect(recorder.Body.String()).To(Equal("console.log('hello');"))
+			})
+
+			It("computes the ETag only when the file changes", func() {
+				modTime := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+				file := &fstest.MapFile{Data: []byte("console.log('hello');"), ModTime: modTime}
+
+				handler.FileSystem = parcello.FromFS(fstest.MapFS{"app.js": file})
+				handler.ServeHTTP(recorder, request)
+				Expect(recorder.Header().Get("ETag")).To(Equal(etag("console.log('hello');")))
+
+				file.Data = []byte("console.log('hallo');")
+
+				recorder = httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				Expect(recorder.Header().Get("ETag")).To(Equal(etag("console.log('hello');")))
+
+				file.ModTime = modTime.Add(time.Second)
+
+				recorder = httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				Expect(recorder.Header().Get("ETag")).To(Equal(etag("console.log('hallo');")))
+			})
+		})
+	})
+
+	Context("when the fingerprints are enabled", func() {
+		BeforeEach(func() {
+			handler.Config = &parcello.HandlerConfig{
+				Fingerprints: true,
+			}
+		})
+
+		It("serves the fingerprinted resource as immutable", func() {
+			name, err := parcello.Fingerprint(handler.FileSystem, "/app.js")
+			Expect(err).NotTo(HaveOccurred())
+
+			request = httptest.NewRequest("GET", name, nil)
+			request.Header.Set("Accept-Encoding", "gzip")
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Cache-Control")).To(Equal("public, max-age=31536000, immutable"))
+			Expect(recorder.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/javascript"))
+		})
+
+		Context("when the fingerprint does not match", func() {
+			It("returns not found", func() {
+				request = httptest.NewRequest("GET", "/app.000000.js", nil)
+				handler.ServeHTTP(recorder, request)
+
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the fingerprints are disabled", func() {
+			It("returns not found", func() {
+				name, err := parcello.Fingerprint(handler.FileSystem, "/app.js")
+				Expect(err).NotTo(HaveOccurred())
+
+				handler.Config = nil
+
+				request = httptest.NewRequest("GET", name, nil)
+				handler.ServeHTTP(recorder, request)
+
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Context("when the resource does not exist", func() {
+		It("returns not found", func() {
+			request = httptest.NewRequest("GET", "/main.css", nil)
+			handler.ServeHTTP(recorder, request)
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when the encoding is not supported", func() {
+		It("returns an error", func() {
+			handler.Config = &parcello.HandlerConfig{
+				Encodings: []string{"lz4"},
+			}
+
+			handler.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+})
+
+var _ = Describe("Handler static site", func() {
+	var (
+		handler  *parcello.Handler
+		recorder *httptest.ResponseRecorder
+	)
+
+	serve := func(method, target string) {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	}
+
+	BeforeEach(func() {
+		handler = &parcello.Handler{
+			FileSystem: parcello.FromFS(fstest.MapFS{
+				"index.html":      &fstest.MapFile{Data: []byte("<html>app</html>")},
+				"404.html":        &fstest.MapFile{Data: []byte("<html>not found</html>")},
+				"js/app.js":       &fstest.MapFile{Data: []byte("console.log('hello');")},
+				"docs/guide.md":   &fstest.MapFile{Data: []byte("# Guide")},
+				"blog/index.html": &fstest.MapFile{Data: []byte("<html>blog</html>")},
+			}),
+			Config: &parcello.HandlerConfig{},
+		}
+	})
+
+	It("lists the directories without index", func() {
+		serve("GET", "/docs/")
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring("guide.md"))
+	})
+
+	It("serves the index of the directory", func() {
+		serve("GET", "/blog/")
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("<html>blog</html>"))
+	})
+
+	It("serves the range of the resource", func() {
+		request := httptest.NewRequest("GET", "/js/app.js", nil)
+		request.Header.Set("Range", "bytes=0-6")
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		Expect(recorder.Code).To(Equal(http.StatusPartialContent))
+		Expect(recorder.Body.String()).To(Equal("console"))
+	})
+
+	Context("when the listing is disabled", func() {
+		BeforeEach(func() {
+			handler.Config.DisableListing = true
+		})
+
+		It("returns not found for the directories without index", func() {
+			serve("GET", "/docs/")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("redirects to the index of the directory", func() {
+			serve("GET", "/blog")
+
+			Expect(recorder.Code).To(Equal(http.StatusMovedPermanently))
+			Expect(recorder.Header().Get("Location")).To(Equal("blog/"))
+		})
+	})
+
+	Context("when the SPA fallback is enabled", func() {
+		BeforeEach(func() {
+			handler.Config.SPA = true
+		})
+
+		It("serves the index for the client-side routes", func() {
+			serve("GET", "/dashboard/settings")
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("<html>app</html>"))
+			Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/html"))
+		})
+
+		It("returns not found for the missing resources with extension", func() {
+			serve("GET", "/js/vendor.js")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("returns not found for the requests that are not GET or HEAD", func() {
+			serve("POST", "/dashboard")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when the not found page is configured", func() {
+		BeforeEach(func() {
+			handler.Config.NotFound = "/404.html"
+		})
+
+		It("serves the page with not found status", func() {
+			serve("GET", "/js/vendor.js")
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			Expect(recorder.Body.String()).To(Equal("<html>not found</html>"))
+			Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/html"))
+		})
+
+		It("does not write the body of the HEAD requests", func() {
+			serve("HEAD", "/js/vendor.js")
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			Expect(recorder.Body.String()).To(BeEmpty())
+		})
+
+		Context("when the page does not exist", func() {
+			It("returns not found", func() {
+				handler.Config.NotFound = "/missing.html"
+				serve("GET", "/js/vendor.js")
+
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				Expect(recorder.Body.String()).To(Equal("404 page not found\n"))
+			})
+		})
+	})
+
+	Context("when the prefix is configured", func() {
+		BeforeEach(func() {
+			handler.Config.Prefix = "/static/"
+		})
+
+		It("strips the prefix", func() {
+			serve("GET", "/static/js/app.js")
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("console.log('hello');"))
+		})
+
+		It("serves the root index", func() {
+			serve("GET", "/static")
+
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("<html>app</html>"))
+		})
+
+		It("returns not found for the paths without the prefix", func() {
+			serve("GET", "/js/app.js")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+
+			serve("GET", "/staticjs/app.js")
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when the cache control is configured", func() {
+		BeforeEach(func() {
+			handler.Config.CacheControl = []parcello.CacheControl{
+				{Pattern: "*.html", Value: "no-cache"},
+				{Pattern: "js/*", Value: "public, max-age=3600"},
+			}
+		})
+
+		It("sets the cache control of the matching resources", func() {
+			serve("GET", "/js/app.js")
+			Expect(recorder.Header().Get("Cache-Control")).To(Equal("public, max-age=3600"))
+
+			serve("GET", "/blog/")
+			Expect(recorder.Header().Get("Cache-Control")).To(Equal("no-cache"))
+
+			serve("GET", "/docs/guide.md")
+			Expect(recorder.Header().Get("Cache-Control")).To(BeEmpty())
+		})
+
+		Context("when the pattern is invalid", func() {
+			It("returns an error", func() {
+				handler.Config.CacheControl = []parcello.CacheControl{
+					{Pattern: "[*", Value: "no-cache"},
+				}
+
+				serve("GET", "/js/app.js")
+				Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+})
+
+func etag(content string) string {
+	return fmt.Sprintf("\"%x\"", sha256.Sum256([]byte(content)))
+}
@@ -0,0 +1,260 @@
+package parcello
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ErrHashMismatch is returned if the content of a bundle entry does not match its recorded hash.
+var ErrHashMismatch = errors.New("Hash mismatch")
+
+// EntryError describes a bundle entry which content is invalid
+type EntryError struct {
+	// Name is the path of the entry in the bundle
+	Name string
+	// Err is the validation error
+	Err error
+}
+
+// Error returns the error message
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+// Unwrap returns the validation error
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// BundleError reports the failures of a resource manager. The bundle may
+// fail to load, some of its entries may be invalid or conflict with the
+// resources of another namespace.
+type BundleError struct {
+	// Err is the error that occurred while the bundle was loaded
+	Err error
+	// Conflicts are the resources that have not been merged in the manager
+	Conflicts []*ConflictError
+	// Entries are the invalid entries that have been detected
+	Entries []*EntryError
+}
+
+// Error returns the error message
+func (e *BundleError) Error() string {
+	messages := []string{}
+
+	if e.Err != nil {
+		messages = append(messages, e.Err.Error())
+	}
+
+	for _, conflict := range e.Conflicts {
+		messages = append(messages, conflict.Error())
+	}
+
+	for _, entry := range e.Entries {
+		messages = append(messages, entry.Error())
+	}
+
+	return fmt.Sprintf("Invalid bundle: %s", strings.Join(messages, "; "))
+}
+
+// Unwrap returns the error that occurred while the bundle was loaded
+func (e *BundleError) Unwrap() error {
+	return e.Err
+}
+
+// Err returns a *BundleError if the default resource manager failed to load
+// the bundle or has detected invalid entries. It can be used by the
+// readiness checks of the services.
+func Err() error {
+	if manager, ok := resourceManager(Manager); ok {
+		return manager.Err()
+	}
+
+	return nil
+}
+
+// Validate validates all entries of the default resource manager and
+// returns a *BundleError if any of them is invalid
+func Validate() error {
+	if manager, ok := resourceManager(Manager); ok {
+		return manager.Validate()
+	}
+
+	return nil
+}
+
+// health records the validation results of the bundle entries
+type health struct {
+	mu        sync.Mutex
+	err       error
+	refused   error
+	conflicts []*ConflictError
+	checked   map[*Node]error
+	entries   []*EntryError
+}
+
+// fail records an error that occurred while the bundle was loaded
+func (h *health) fail(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err == nil {
+		h.err = err
+	}
+}
+
+// refuse records an error because of which the resources must not be served
+func (h *health) refuse(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err == nil {
+		h.err = err
+	}
+
+	if h.refused == nil {
+		h.refused = err
+	}
+}
+
+// refusal returns the error because of which the resources must not be
+// served
+func (h *health) refusal() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.refused
+}
+
+// conflict records the resources that have not been merged
+func (h *health) conflict(err *ConflictError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the conflict may be detected by the namespace and the default manager
+	for _, conflict := range h.conflicts {
+		if conflict.Error() == err.Error() {
+			return
+		}
+	}
+
+	h.conflicts = append(h.conflicts, err)
+}
+
+// result returns the recorded validation result of the node
+func (h *health) result(node *Node) (error, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err, ok := h.checked[node]
+	return err, ok
+}
+
+// record records the validation result of the node and returns it. Only the
+// successful validations and the hash mismatches are recorded, the other
+// errors (for instance I/O errors) may be transient and they are retried.
+func (h *health) record(node *Node, source Source, err error) error {
+	if err != nil && !errors.Is(err, ErrHashMismatch) {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.checked == nil {
+		h.checked = map[*Node]error{}
+	}
+
+	if _, ok := h.checked[node]; ok {
+		return err
+	}
+
+	h.checked[node] = err
+
+	if err != nil {
+		name := entryName(node, source)
+
+		// the entry may be loaded by the default manager and its namespace
+		for _, entry := range h.entries {
+			if entry.Name == name && entry.Err.Error() == err.Error() {
+				return err
+			}
+		}
+
+		h.entries = append(h.entries, &EntryError{Name: name, Err: err})
+	}
+
+	return err
+}
+
+// report returns the recorded failures together with given (not recorded)
+// entry errors as *BundleError
+func (h *health) report(failed ...*EntryError) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err == nil && len(h.conflicts) == 0 && len(h.entries) == 0 && len(failed) == 0 {
+		return nil
+	}
+
+	conflicts := make([]*ConflictError, len(h.conflicts))
+	copy(conflicts, h.conflicts)
+
+	entries := make([]*EntryError, len(h.entries), len(h.entries)+len(failed))
+	copy(entries, h.entries)
+	entries = append(entries, failed...)
+
+	return &BundleError{Err: h.err, Conflicts: conflicts, Entries: entries}
+}
+
+// entryName returns the path of the node in the bundle
+func entryName(node *Node, source Source) string {
+	if source, ok := source.(namedSource); ok {
+		return source.Name()
+	}
+
+	return node.Name
+}
+
+// namedSource is implemented by the sources that know the path of their
+// entry in the bundle
+type namedSource interface {
+	Name() string
+}
+
+// hashReader validates the SHA-256 hash of the content once it is read
+// completely
+type hashReader struct {
+	io.ReadCloser
+	digest   io.Writer
+	sum      func() string
+	expected string
+}
+
+func newHashReader(reader io.ReadCloser, expected string) *hashReader {
+	digest := sha256.New()
+
+	return &hashReader{
+		ReadCloser: reader,
+		digest:     digest,
+		sum:        func() string { return hex.EncodeToString(digest.Sum(nil)) },
+		expected:   expected,
+	}
+}
+
+// Read reads the content and validates its hash at the end
+func (r *hashReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	_, _ = r.digest.Write(p[:n])
+
+	if err == io.EOF && r.sum() != r.expected {
+		return n, ErrHashMismatch
+	}
+
+	return n, err
+}
@@ -0,0 +1,286 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Health", func() {
+	var (
+		compressor *parcello.ZipCompressor
+		manager    *parcello.ResourceManager
+		body       []byte
+	)
+
+	BeforeEach(func() {
+		compressor = &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.FromFS(fstest.MapFS{
+				"index.html":     &fstest.MapFile{Data: []byte("<html></html>")},
+				"css/app.css":    &fstest.MapFile{Data: []byte("body {}")},
+				"img/logo.png":   &fstest.MapFile{Data: []byte("PNG")},
+				"docs/README.md": &fstest.MapFile{Data: []byte("# README")},
+			}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		body = tamper(bundle.Body, "index.html", "<html>tampered</html>")
+		body = tamper(body, "css/app.css", "body { color: red; }")
+
+		manager = &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(body))).To(Succeed())
+	})
+
+	It("does not report errors of the resources that have not been opened", func() {
+		Expect(manager.Err()).To(Succeed())
+
+		data, err := manager.ReadFile("/docs/README.md")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("# README"))
+
+		Expect(manager.Err()).To(Succeed())
+	})
+
+	It("reports the entries which hash does not match", func() {
+		_, err := manager.Open("/index.html")
+		Expect(err).To(MatchError("open index.html: Hash mismatch"))
+
+		_, err = manager.Open("/index.html")
+		Expect(err).To(MatchError("open index.html: Hash mismatch"))
+
+		err = manager.Err()
+		Expect(err).To(MatchError("Invalid bundle: index.html: Hash mismatch"))
+
+		bundleErr := &parcello.BundleError{}
+		Expect(errors.As(err, &bundleErr)).To(BeTrue())
+		Expect(bundleErr.Err).To(BeNil())
+		Expect(bundleErr.Entries).To(HaveLen(1))
+		Expect(bundleErr.Entries[0].Name).To(Equal("index.html"))
+		Expect(errors.Is(bundleErr.Entries[0], parcello.ErrHashMismatch)).To(BeTrue())
+	})
+
+	It("shares the errors with the sub-managers", func() {
+		dir, err := manager.Dir("/css")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = dir.Open("/app.css")
+		Expect(err).To(MatchError("open app.css: Hash mismatch"))
+
+		Expect(manager.Err()).To(MatchError("Invalid bundle: css/app.css: Hash mismatch"))
+	})
+
+	Context("when the entry cannot be read", func() {
+		It("does not record the error", func() {
+			reader := &flakyReader{ReaderAt: bytes.NewReader(body)}
+
+			manager = &parcello.ResourceManager{}
+			Expect(manager.Add(&parcello.Resource{Body: reader, Size: int64(len(body))})).To(Succeed())
+
+			reader.fail = true
+
+			_, err := manager.ReadFile("/docs/README.md")
+			Expect(err).To(MatchError(ContainSubstring("oh no!")))
+			Expect(manager.Err()).To(Succeed())
+
+			err = manager.Validate()
+			Expect(err).To(MatchError(ContainSubstring("docs/README.md: oh no!")))
+			Expect(manager.Err()).To(Succeed())
+
+			reader.fail = false
+
+			data, err := manager.ReadFile("/docs/README.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("# README"))
+		})
+	})
+
+	Context("when the entries are stored", func() {
+		BeforeEach(func() {
+			compressor.Config.Compression = "store"
+		})
+
+		It("validates the entries on first open", func() {
+			_, err := manager.Open("/index.html")
+			Expect(err).To(MatchError("open index.html: Hash mismatch"))
+
+			file, err := manager.Open("/img/logo.png")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			Expect(manager.Err()).To(MatchError("Invalid bundle: index.html: Hash mismatch"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("reports all invalid entries", func() {
+			err := manager.Validate()
+
+			bundleErr := &parcello.BundleError{}
+			Expect(errors.As(err, &bundleErr)).To(BeTrue())
+			Expect(bundleErr.Entries).To(HaveLen(2))
+			Expect(manager.Err()).To(Equal(err))
+		})
+
+		Context("when the bundle is valid", func() {
+			It("does not report errors", func() {
+				bundle, err := compressor.Compress(&parcello.CompressorContext{
+					FileSystem: parcello.Dir("./fixture"),
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				manager = &parcello.ResourceManager{}
+				Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+				Expect(manager.Validate()).To(Succeed())
+			})
+		})
+	})
+
+	Describe("NewResourceManager", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "parcello")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		newManager := func(data []byte) *parcello.ResourceManager {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "binary"), data, 0600)).To(Succeed())
+
+			manager, err := parcello.NewResourceManager(&parcello.ResourceManagerConfig{
+				Path:       "binary",
+				FileSystem: parcello.Dir(dir),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return manager
+		}
+
+		It("loads the bundle appended to the binary", func() {
+			manager := newManager(append([]byte("binary"), body...))
+			Expect(manager.Err()).To(Succeed())
+
+			data, err := manager.ReadFile("/docs/README.md")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("# README"))
+		})
+
+		Context("when the binary does not have a bundle", func() {
+			It("does not report errors", func() {
+				manager := newManager([]byte("binary"))
+				Expect(manager.Err()).To(Succeed())
+			})
+
+			It("returns the root directory", func() {
+				manager := newManager([]byte("binary"))
+
+				dir, err := manager.Dir("/")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dir).NotTo(BeNil())
+			})
+
+			It("returns an error when a file is created", func() {
+				manager := newManager([]byte("binary"))
+
+				file, err := manager.OpenFile("/a.txt", os.O_CREATE|os.O_RDWR, 0600)
+				Expect(file).To(BeNil())
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the bundle is truncated", func() {
+			It("is handled as a binary without a bundle", func() {
+				data := append([]byte("binary"), body[:len(body)/2]...)
+				Expect(newManager(data).Err()).To(Succeed())
+			})
+
+			Context("when the public key is provided", func() {
+				It("returns an error", func() {
+					publicKey, _, err := ed25519.GenerateKey(nil)
+					Expect(err).NotTo(HaveOccurred())
+
+					data := append([]byte("binary"), body[:len(body)/2]...)
+					Expect(ioutil.WriteFile(filepath.Join(dir, "binary"), data, 0600)).To(Succeed())
+
+					manager, err := parcello.NewResourceManager(&parcello.ResourceManagerConfig{
+						Path:       "binary",
+						FileSystem: parcello.Dir(dir),
+						PublicKey:  publicKey,
+					})
+					Expect(manager).To(BeNil())
+					Expect(err).To(MatchError(zip.ErrFormat))
+				})
+			})
+		})
+
+		Context("when the bundle cannot be loaded", func() {
+			It("reports the error", func() {
+				buffer := &bytes.Buffer{}
+
+				writer := zip.NewWriter(buffer)
+				writer.RegisterCompressor(99, func(w io.Writer) (io.WriteCloser, error) {
+					return &nopWriteCloser{Writer: w}, nil
+				})
+
+				_, err := writer.CreateHeader(&zip.FileHeader{Name: "index.html", Method: 99})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(writer.Close()).To(Succeed())
+
+				err = newManager(append([]byte("binary"), buffer.Bytes()...)).Err()
+				Expect(err).To(BeAssignableToTypeOf(&parcello.BundleError{}))
+				Expect(errors.Is(err, zip.ErrAlgorithm)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("BundleError", func() {
+		It("returns the error message", func() {
+			err := &parcello.BundleError{
+				Err: fmt.Errorf("oh no!"),
+				Entries: []*parcello.EntryError{
+					{Name: "index.html", Err: parcello.ErrHashMismatch},
+				},
+			}
+
+			Expect(err).To(MatchError("Invalid bundle: oh no!; index.html: Hash mismatch"))
+		})
+	})
+})
+
+type flakyReader struct {
+	io.ReaderAt
+	fail bool
+}
+
+func (r *flakyReader) ReadAt(p []byte, offset int64) (int, error) {
+	if r.fail {
+		return 0, fmt.Errorf("oh no!")
+	}
+
+	return r.ReaderAt.ReadAt(p, offset)
+}
@@ -0,0 +1,251 @@
+package parcello
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the files that contain the ignore rules of
+// the directory they reside in and its sub-directories. They follow the
+// .gitignore semantics.
+const IgnoreFileName = ".parcelloignore"
+
+// defaultIgnorePatterns are ignored unless they are negated
+var defaultIgnorePatterns = []string{"*.go", IgnoreFileName}
+
+// ignoreRule is a single line of an ignore file
+type ignoreRule struct {
+	// base is the directory the rule is relative to
+	base string
+	// pattern is the glob pattern of the rule
+	pattern string
+	// negate re-includes the matching files
+	negate bool
+	// dirOnly matches only directories
+	dirOnly bool
+	// anchored matches the path relative to base instead of the name
+	anchored bool
+}
+
+// parseRule parses a .gitignore line. It returns nil if the line is blank or
+// a comment.
+func parseRule(base, line string) (*ignoreRule, error) {
+	line = strings.TrimRight(line, " \t\r")
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	rule := &ignoreRule{base: base}
+
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	rule.anchored = strings.Contains(line, "/")
+	rule.pattern = strings.TrimPrefix(line, "/")
+
+	if rule.pattern == "" {
+		return nil, nil
+	}
+
+	if _, err := path.Match(rule.pattern, ""); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// match returns true if the rule matches the slash-separated path
+func (r *ignoreRule) match(name string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+
+	if r.base != "." {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+
+		name = strings.TrimPrefix(name, r.base+"/")
+	}
+
+	if r.anchored {
+		return matchGlob(r.pattern, name)
+	}
+
+	matched, _ := path.Match(r.pattern, path.Base(name))
+	return matched
+}
+
+// matchGlob matches the slash-separated path against the pattern. The "**"
+// segment matches zero or more directories.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for index := 0; index <= len(names); index++ {
+				if matchSegments(patterns[1:], names[index:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		if matched, _ := path.Match(patterns[0], names[0]); !matched {
+			return false
+		}
+
+		patterns = patterns[1:]
+		names = names[1:]
+	}
+
+	return len(names) == 0
+}
+
+// ignorer decides which resources are ignored during a single traversal. It
+// collects the rules of the ignore files of the visited directories.
+type ignorer struct {
+	fileSystem FileSystem
+	// defaults are the rules of the default patterns
+	defaults []*ignoreRule
+	// rules are the rules of the ignore files in the order of the traversal
+	rules []*ignoreRule
+	// patterns are the rules of the configuration, which take precedence
+	// over the ignore files like the command line patterns of git
+	patterns []*ignoreRule
+	// includes are the patterns of the included files
+	includes []*ignoreRule
+}
+
+func (cfg *CompressorConfig) ignorer(fileSystem FileSystem) (*ignorer, error) {
+	var err error
+
+	ignorer := &ignorer{fileSystem: fileSystem}
+
+	if ignorer.defaults, err = parseRules(defaultIgnorePatterns); err != nil {
+		return nil, err
+	}
+
+	if ignorer.patterns, err = parseRules(cfg.IgnorePatterns); err != nil {
+		return nil, err
+	}
+
+	if ignorer.includes, err = parseRules(cfg.IncludePatterns); err != nil {
+		return nil, err
+	}
+
+	return ignorer, nil
+}
+
+// parseRules parses the patterns relative to the root
+func parseRules(patterns []string) ([]*ignoreRule, error) {
+	rules := []*ignoreRule{}
+
+	for _, pattern := range patterns {
+		rule, err := parseRule(".", pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// ignored returns true if the resource should not be bundled. The rules are
+// applied in the order of precedence: the default patterns, the ignore files
+// and the patterns of the configuration. The last matching rule wins. The
+// include patterns apply only to files.
+func (i *ignorer) ignored(name string, info os.FileInfo) bool {
+	name = slashPath(name)
+
+	if name == "." {
+		return false
+	}
+
+	ignored := false
+
+	for _, rules := range [][]*ignoreRule{i.defaults, i.rules, i.patterns} {
+		for _, rule := range rules {
+			if rule.match(name, info.IsDir()) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	if ignored || info.IsDir() || len(i.includes) == 0 {
+		return ignored
+	}
+
+	for _, rule := range i.includes {
+		if rule.match(name, false) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// load reads the ignore file of the directory if there is one
+func (i *ignorer) load(dir string) error {
+	dir = slashPath(dir)
+
+	filename := path.Join(dir, IgnoreFileName)
+
+	file, err := i.fileSystem.OpenFile(filename, os.O_RDONLY, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		rule, err := parseRule(dir, scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+
+		if rule != nil {
+			i.rules = append(i.rules, rule)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// slashPath returns the clean slash-separated path relative to the root
+func slashPath(name string) string {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+	return path.Clean(name)
+}
@@ -0,0 +1,176 @@
+package parcello_test
+
+import (
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Ignore", func() {
+	var (
+		compressor *parcello.EmbedCompressor
+		fileSystem fstest.MapFS
+	)
+
+	files := func() []string {
+		ctx := &parcello.CompressorContext{
+			FileSystem: parcello.FromFS(fileSystem),
+		}
+
+		bundle, err := compressor.Compress(ctx)
+		Expect(err).To(BeNil())
+
+		if bundle == nil {
+			return nil
+		}
+
+		return bundle.Files
+	}
+
+	BeforeEach(func() {
+		fileSystem = fstest.MapFS{
+			"main.go":                   &fstest.MapFile{},
+			"README.md":                 &fstest.MapFile{},
+			"web/src/app.ts":            &fstest.MapFile{},
+			"web/dist/app.js":           &fstest.MapFile{},
+			"web/dist/app.js.map":       &fstest.MapFile{},
+			"web/dist/css/site.css":     &fstest.MapFile{},
+			"web/dist/css/site.css.map": &fstest.MapFile{},
+		}
+
+		compressor = &parcello.EmbedCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+	})
+
+	It("ignores the go files by default", func() {
+		Expect(files()).NotTo(ContainElement("main.go"))
+	})
+
+	Context("when the go files are negated", func() {
+		It("includes them", func() {
+			compressor.Config.IgnorePatterns = []string{"!*.go"}
+			Expect(files()).To(ContainElement("main.go"))
+		})
+	})
+
+	Context("when the pattern contains a double star", func() {
+		It("matches any number of directories", func() {
+			compressor.Config.IgnorePatterns = []string{"web/**/*.map"}
+
+			Expect(files()).To(Equal([]string{
+				"README.md",
+				"web/dist/app.js",
+				"web/dist/css/site.css",
+				"web/src/app.ts",
+			}))
+		})
+	})
+
+	Context("when the pattern is negated", func() {
+		It("re-includes the matching files", func() {
+			compressor.Config.IgnorePatterns = []string{"*.map", "!site.css.map"}
+
+			Expect(files()).To(Equal([]string{
+				"README.md",
+				"web/dist/app.js",
+				"web/dist/css/site.css",
+				"web/dist/css/site.css.map",
+				"web/src/app.ts",
+			}))
+		})
+	})
+
+	Context("when the pattern matches only directories", func() {
+		It("ignores the directories", func() {
+			fileSystem["web/src/dist"] = &fstest.MapFile{}
+			compressor.Config.IgnorePatterns = []string{"src/", "dist"}
+
+			Expect(files()).To(Equal([]string{
+				"README.md",
+			}))
+		})
+	})
+
+	Context("when the include patterns are provided", func() {
+		It("includes only the matching files", func() {
+			compressor.Config.IncludePatterns = []string{"web/dist/**"}
+			compressor.Config.IgnorePatterns = []string{"*.map"}
+
+			Expect(files()).To(Equal([]string{
+				"web/dist/app.js",
+				"web/dist/css/site.css",
+			}))
+		})
+	})
+
+	Context("when the directory has an ignore file", func() {
+		BeforeEach(func() {
+			fileSystem["web/.parcelloignore"] = &fstest.MapFile{
+				Data: []byte("# source maps\n*.map\n!dist/css/*.map\n/src\n"),
+			}
+		})
+
+		It("applies its rules to the directory", func() {
+			Expect(files()).To(Equal([]string{
+				"README.md",
+				"web/dist/app.js",
+				"web/dist/css/site.css",
+				"web/dist/css/site.css.map",
+			}))
+		})
+
+		Context("when the ignore patterns are provided", func() {
+			It("applies them after the rules of the ignore file", func() {
+				compressor.Config.IgnorePatterns = []string{"!**/*.map"}
+
+				Expect(files()).To(Equal([]string{
+					"README.md",
+					"web/dist/app.js",
+					"web/dist/app.js.map",
+					"web/dist/css/site.css",
+					"web/dist/css/site.css.map",
+				}))
+			})
+		})
+
+		It("does not apply its rules to the parent directory", func() {
+			fileSystem["README.map"] = &fstest.MapFile{}
+			Expect(files()).To(ContainElement("README.map"))
+		})
+
+		Context("when the rule is invalid", func() {
+			It("returns an error", func() {
+				fileSystem["web/.parcelloignore"].Data = []byte("[*")
+
+				ctx := &parcello.CompressorContext{
+					FileSystem: parcello.FromFS(fileSystem),
+				}
+
+				bundle, err := compressor.Compress(ctx)
+				Expect(err).To(MatchError("web/.parcelloignore: syntax error in pattern"))
+				Expect(bundle).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the include pattern is invalid", func() {
+		It("returns an error", func() {
+			compressor.Config.IncludePatterns = []string{"[*"}
+
+			ctx := &parcello.CompressorContext{
+				FileSystem: parcello.FromFS(fileSystem),
+			}
+
+			bundle, err := compressor.Compress(ctx)
+			Expect(err).To(MatchError("syntax error in pattern"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+})
@@ -75,6 +75,40 @@ var _ = Describe("Parcel", func() {
 			Expect(string(data)).To(ContainSubstring("parcello.AddResource"))
 		})
 
+		Context("when the package is in a module", func() {
+			It("registers the resource under the import path of the package", func() {
+				path := filepath.Join(cmd.Dir, "go.mod")
+				Expect(ioutil.WriteFile(path, []byte("module github.com/phogolabs/example\n"), 0600)).To(Succeed())
+
+				cmd.Args = append(cmd.Args, "-r", "-d", "database", "-b", "database")
+
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+
+				data, err := ioutil.ReadFile(filepath.Join(cmd.Dir, "database", "resource.go"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(ContainSubstring("parcello.AddNamespaceResource(\"github.com/phogolabs/example/database\", []byte{"))
+			})
+
+			Context("when the namespace is provided", func() {
+				It("registers the resource under the namespace", func() {
+					path := filepath.Join(cmd.Dir, "go.mod")
+					Expect(ioutil.WriteFile(path, []byte("module github.com/phogolabs/example\n"), 0600)).To(Succeed())
+
+					cmd.Args = append(cmd.Args, "-r", "-n", "github.com/phogolabs/resources")
+
+					session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(session).Should(gexec.Exit(0))
+
+					data, err := ioutil.ReadFile(resource)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(data)).To(ContainSubstring("parcello.AddNamespaceResource(\"github.com/phogolabs/resources\", []byte{"))
+				})
+			})
+		})
+
 		Context("when the commands.sql is ignored", func() {
 			BeforeEach(func() {
 				args = append(args, "-r", "-i", "commands.sql")
@@ -172,6 +206,39 @@ var _ = Describe("Parcel", func() {
 			})
 		})
 
+		Context("when the resource type is embed", func() {
+			BeforeEach(func() {
+				args = []string{"-r", "-t", "embed"}
+			})
+
+			It("generates go:embed directives", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+
+				Expect(session.Out).To(gbytes.Say("Embedding 'database/command/commands.sql'"))
+				Expect(resource).To(BeARegularFile())
+
+				data, err := ioutil.ReadFile(resource)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(ContainSubstring("//go:embed database/command/commands.sql"))
+				Expect(string(data)).To(ContainSubstring("//go:embed database/main.sql"))
+				Expect(string(data)).To(ContainSubstring("parcello.AddFS(resourceFS)"))
+			})
+
+			Context("when the resource directory is outside of the package", func() {
+				BeforeEach(func() {
+					args = append(args, "-b", "./database/command", "-d", "./database")
+				})
+
+				It("returns an error", func() {
+					session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(session).Should(gexec.Exit(101))
+				})
+			})
+		})
+
 		Context("when the resource type is bundle", func() {
 			BeforeEach(func() {
 				args = []string{"-r", "-t", "bundle", "-b", binaryPath}
@@ -184,4 +251,149 @@ var _ = Describe("Parcel", func() {
 			})
 		})
 	})
+
+	Describe("Config", func() {
+		run := func(dir string, args ...string) *gexec.Session {
+			command := exec.Command(embedoPath, args...)
+			command.Dir = dir
+
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			return session
+		}
+
+		JustBeforeEach(func() {
+			config := "bundles:\n  - resource-dir: database\n    bundle-path: bundle\n    recursive: true\n"
+
+			path := filepath.Join(cmd.Dir, "parcello.yaml")
+			Expect(ioutil.WriteFile(path, []byte(config), 0600)).To(Succeed())
+		})
+
+		It("builds the bundles of the discovered configuration file", func() {
+			session := run(filepath.Join(cmd.Dir, "database"))
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(filepath.Join(cmd.Dir, "bundle", "resource.go")).To(BeARegularFile())
+		})
+
+		Context("when the bundle flags are given", func() {
+			It("does not use the discovered configuration file", func() {
+				session := run(filepath.Join(cmd.Dir, "database"), "-b", "../other")
+				Eventually(session).Should(gexec.Exit(0))
+
+				Expect(filepath.Join(cmd.Dir, "other", "resource.go")).To(BeARegularFile())
+				Expect(filepath.Join(cmd.Dir, "bundle", "resource.go")).NotTo(BeAnExistingFile())
+			})
+
+			It("cannot be combined with the configuration file", func() {
+				session := run(cmd.Dir, "--config", "parcello.yaml", "-r")
+				Eventually(session).Should(gexec.Exit(101))
+
+				Expect(session.Err).To(gbytes.Say("The flags --recursive cannot be combined with the configuration file 'parcello.yaml'"))
+			})
+		})
+
+		It("checks the bundles of the configuration file", func() {
+			Eventually(run(cmd.Dir)).Should(gexec.Exit(0))
+
+			session := run(cmd.Dir, "check")
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("is up to date"))
+
+			path := filepath.Join(cmd.Dir, "database", "main.sql")
+			Expect(ioutil.WriteFile(path, []byte("changed"), 0700)).To(Succeed())
+
+			session = run(cmd.Dir, "check")
+			Eventually(session).Should(gexec.Exit(103))
+			Expect(session.Out).To(gbytes.Say(`~ main.sql \(size 4 -> 7`))
+		})
+	})
+
+	Describe("Check", func() {
+		run := func(args ...string) *gexec.Session {
+			command := exec.Command(embedoPath, args...)
+			command.Dir = cmd.Dir
+
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			return session
+		}
+
+		JustBeforeEach(func() {
+			Eventually(run("-r", "-q")).Should(gexec.Exit(0))
+		})
+
+		It("reports that the bundle is up to date", func() {
+			session := run("check", "-r")
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("is up to date"))
+		})
+
+		Context("when the resources have changed", func() {
+			It("reports the differences", func() {
+				path := filepath.Join(cmd.Dir, "database", "main.sql")
+				Expect(ioutil.WriteFile(path, []byte("changed"), 0700)).To(Succeed())
+
+				session := run("check", "-r")
+				Eventually(session).Should(gexec.Exit(103))
+				Expect(session.Out).To(gbytes.Say(`~ database/main.sql \(size 4 -> 7`))
+				Expect(session.Err).To(gbytes.Say("is out of date"))
+			})
+		})
+
+		Context("when the resource type is embed", func() {
+			It("checks the files of the go:embed directives", func() {
+				Eventually(run("-r", "-q", "-t", "embed")).Should(gexec.Exit(0))
+
+				session := run("check", "-r", "-t", "embed")
+				Eventually(session).Should(gexec.Exit(0))
+				Expect(session.Out).To(gbytes.Say("is up to date"))
+
+				path := filepath.Join(cmd.Dir, "database", "users.sql")
+				Expect(ioutil.WriteFile(path, []byte("users"), 0700)).To(Succeed())
+
+				path = filepath.Join(cmd.Dir, "database", "command", "commands.sql")
+				Expect(os.Remove(path)).To(Succeed())
+
+				session = run("check", "-r", "-t", "embed")
+				Eventually(session).Should(gexec.Exit(103))
+				Expect(session.Out).To(gbytes.Say(`- database/command/commands.sql`))
+				Expect(session.Out).To(gbytes.Say(`\+ database/users.sql \(size 5`))
+			})
+
+			It("does not report the ignored files", func() {
+				Eventually(run("-r", "-q", "-t", "embed")).Should(gexec.Exit(0))
+
+				path := filepath.Join(cmd.Dir, "database", "users.sql")
+				Expect(ioutil.WriteFile(path, []byte("users"), 0700)).To(Succeed())
+
+				session := run("check", "-r", "-t", "embed", "-i", "users.sql")
+				Eventually(session).Should(gexec.Exit(0))
+			})
+		})
+
+		Context("when the resource type is bundle", func() {
+			It("checks the bundle of the binary", func() {
+				Eventually(run("-r", "-q", "-t", "bundle", "-b", binaryPath)).Should(gexec.Exit(0))
+
+				session := run("check", "-r", "-t", "bundle", "-b", binaryPath)
+				Eventually(session).Should(gexec.Exit(0))
+
+				path := filepath.Join(cmd.Dir, "database", "command", "commands.sql")
+				Expect(os.Remove(path)).To(Succeed())
+
+				session = run("check", "-r", "-t", "bundle", "-b", binaryPath)
+				Eventually(session).Should(gexec.Exit(103))
+				Expect(session.Out).To(gbytes.Say(`- database/command/commands.sql`))
+			})
+
+			Context("when the binary does not have a bundle", func() {
+				It("reports the missing resources", func() {
+					session := run("check", "-r", "-t", "bundle", "-b", binaryPath)
+					Eventually(session).Should(gexec.Exit(103))
+					Expect(session.Out).To(gbytes.Say(`\+ database/main.sql`))
+				})
+			})
+		})
+	})
 })
@@ -2,12 +2,16 @@ package parcello
 
 import (
 	"archive/zip"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +27,10 @@ var (
 	ErrWriteOnly = errors.New("File is write-only")
 	// ErrIsDirectory is returned if the file under operation is not a regular file but a directory.
 	ErrIsDirectory = errors.New("Is directory")
+	// ErrNotDirectory is returned if the file under operation is not a directory.
+	ErrNotDirectory = errors.New("Not a directory")
+	// ErrNotSupported is returned if the operation is not supported by the file.
+	ErrNotSupported = errors.New("Not supported")
 )
 
 var (
@@ -46,6 +54,17 @@ func ManagerAt(path string) FileSystemManager {
 	return mngr
 }
 
+// Verify verifies the signatures of the bundles of the default resource
+// manager. It should be called at startup once all resources are added. The
+// resources are not verified in dev mode. See ResourceManager.Verify.
+func Verify(key ed25519.PublicKey) error {
+	if manager, ok := resourceManager(Manager); ok {
+		return manager.Verify(key)
+	}
+
+	return nil
+}
+
 // AddResource adds resource to the default resource manager
 // Note that the method may panic if the resource not exists
 func AddResource(resource []byte) {
@@ -54,24 +73,49 @@ func AddResource(resource []byte) {
 	}
 }
 
+// AddFS adds file system (for instance embed.FS) to the default resource manager
+// Note that the method may panic if the file system cannot be read
+func AddFS(fileSystem fs.FS) {
+	if err := Manager.Add(FSResource(fileSystem)); err != nil {
+		panic(err)
+	}
+}
+
 // ResourceManagerConfig represents the configuration for Resource Manager
 type ResourceManagerConfig struct {
 	// Path to the archive
 	Path string
 	// FileSystem that stores the archive
 	FileSystem FileSystem
+	// CacheSize is the maximum size in bytes of the decompressed content that
+	// is kept in memory (zero disables the cache)
+	CacheSize int64
+	// PublicKey verifies the signature of the bundle (see ResourceManager.PublicKey)
+	PublicKey ed25519.PublicKey
 }
 
 // ResourceManager represents a virtual in memory file system
 type ResourceManager struct {
-	cfg  *ResourceManagerConfig
-	rw   sync.RWMutex
-	root *Node
+	cfg      *ResourceManagerConfig
+	rw       sync.RWMutex
+	root     *Node
+	manifest manifest
+	bundles  []*zip.Reader
+	once     sync.Once
+	checks   *health
 	// NewReader creates a new ZIP Reader
 	NewReader func(io.ReaderAt, int64) (*zip.Reader, error)
+	// Cache keeps the decompressed content of the recently opened resources.
+	// If it is nil, the resources are decompressed every time they are opened.
+	Cache *ContentCache
+	// PublicKey verifies the signature of the added bundles. If it is nil,
+	// the bundles are not verified.
+	PublicKey ed25519.PublicKey
 }
 
-// DefaultManager creates a FileSystemManager based on whether dev mode is enabled
+// DefaultManager creates a FileSystemManager based on whether dev mode is
+// enabled. If the resources of the executable cannot be loaded, the returned
+// manager reports the failure by Err.
 func DefaultManager(executable ExecutableFunc) FileSystemManager {
 	mode := os.Getenv("PARCELLO_DEV_ENABLED")
 
@@ -81,57 +125,207 @@ func DefaultManager(executable ExecutableFunc) FileSystemManager {
 
 	path, err := executable()
 	if err != nil {
-		panic(err)
+		return overlay(failedManager(err))
 	}
 
 	dir, path := filepath.Split(path)
 
+	size, err := strconv.ParseInt(getenv("PARCELLO_CACHE_SIZE", "0"), 10, 64)
+	if err != nil {
+		return overlay(failedManager(err))
+	}
+
 	cfg := &ResourceManagerConfig{
 		Path:       path,
 		FileSystem: Dir(dir),
+		CacheSize:  size,
 	}
 
 	manager, err := NewResourceManager(cfg)
 	if err != nil {
-		panic(err)
+		return overlay(failedManager(err))
 	}
 
-	return manager
+	return overlay(manager)
+}
+
+// overlay stacks the directories of PARCELLO_OVERLAY_DIR (if any) over the
+// manager. The directories are separated by os.PathListSeparator and ordered
+// by precedence.
+func overlay(manager FileSystemManager) FileSystemManager {
+	value := os.Getenv("PARCELLO_OVERLAY_DIR")
+	if value == "" {
+		return manager
+	}
+
+	layers := []FileSystemManager{}
+
+	for _, dir := range filepath.SplitList(value) {
+		layers = append(layers, Dir(dir))
+	}
+
+	return NewOverlay(append(layers, manager)...)
+}
+
+// resourceManager returns the resource manager of given manager, which may
+// be the bottom layer of an overlay
+func resourceManager(manager FileSystemManager) (*ResourceManager, bool) {
+	switch manager := manager.(type) {
+	case *ResourceManager:
+		return manager, true
+	case *Overlay:
+		for index := len(manager.Layers) - 1; index >= 0; index-- {
+			if layer, ok := resourceManager(manager.Layers[index]); ok {
+				return layer, true
+			}
+		}
+	}
+
+	return nil, false
 }
 
 // NewResourceManager creates a new manager
 func NewResourceManager(cfg *ResourceManagerConfig) (*ResourceManager, error) {
-	manager := &ResourceManager{cfg: cfg}
+	manager := &ResourceManager{
+		cfg:       cfg,
+		root:      &Node{Name: "/", IsDir: true, Mutex: &sync.RWMutex{}},
+		PublicKey: cfg.PublicKey,
+	}
+
+	if cfg.CacheSize > 0 {
+		manager.Cache = NewContentCache(cfg.CacheSize)
+	}
 
 	file, err := cfg.FileSystem.OpenFile(cfg.Path, os.O_RDONLY, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	defer file.Close()
-
 	info, err := file.Stat()
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 
+	// the file is not closed, because the resources are decompressed on demand
+
 	resource := &Resource{
 		Body: file,
 		Size: info.Size(),
 	}
 
-	_ = manager.Add(resource)
+	// the bundle is appended to the end of the executable
+	manager.NewReader = zip.NewReader
+
+	if err := manager.Add(resource); err != nil {
+		// the tampered bundles are refused when the public key is provided
+		if cfg.PublicKey != nil {
+			file.Close()
+			return nil, err
+		}
+
+		// the executable does not contain a bundle
+		if err == zip.ErrFormat {
+			return manager, nil
+		}
+
+		manager.health().fail(err)
+	}
 
 	return manager, nil
 }
 
-// Add adds resource to the manager
+// Err returns a *BundleError if the bundle of the executable failed to load
+// or invalid entries have been detected while the resources were opened
+func (m *ResourceManager) Err() error {
+	return m.health().report()
+}
+
+// Validate validates the content of all resources, including the resources
+// of the namespaces, and returns a *BundleError if any of them is invalid.
+// The entries that cannot be read are reported as well, but they are not
+// recorded by Err, because the failure may be transient.
+func (m *ResourceManager) Validate() error {
+	failed := []*EntryError{}
+
+	for _, manager := range append([]*ResourceManager{m}, registry.linked(m)...) {
+		manager.rw.RLock()
+
+		if manager.root != nil {
+			failed = manager.validateAll(manager.root, failed)
+		}
+
+		manager.rw.RUnlock()
+	}
+
+	return m.health().report(failed...)
+}
+
+func (m *ResourceManager) validateAll(node *Node, failed []*EntryError) []*EntryError {
+	for _, child := range node.Children {
+		if child.IsDir {
+			failed = m.validateAll(child, failed)
+			continue
+		}
+
+		if child.Mutex == nil {
+			continue
+		}
+
+		child.Mutex.RLock()
+		source := child.Source
+		child.Mutex.RUnlock()
+
+		if source == nil {
+			continue
+		}
+
+		if err := m.validate(child, source); err != nil && !errors.Is(err, ErrHashMismatch) {
+			failed = append(failed, &EntryError{Name: entryName(child, source), Err: err})
+		}
+	}
+
+	return failed
+}
+
+func (m *ResourceManager) health() *health {
+	m.once.Do(func() {
+		if m.checks == nil {
+			m.checks = &health{}
+		}
+	})
+
+	return m.checks
+}
+
+func failedManager(err error) *ResourceManager {
+	manager := &ResourceManager{root: &Node{Name: "/", IsDir: true, Mutex: &sync.RWMutex{}}}
+	manager.health().fail(err)
+	return manager
+}
+
+// Add adds resource to the manager. If any of the resources already exists,
+// the manager is not modified and a *ConflictError is returned.
 func (m *ResourceManager) Add(resource *Resource) error {
+	return m.add(resource, false)
+}
+
+// merge adds resource to the manager, but it skips the resources that
+// already exist. The skipped resources are reported by a *ConflictError.
+func (m *ResourceManager) merge(resource *Resource) error {
+	return m.add(resource, true)
+}
+
+func (m *ResourceManager) add(resource *Resource, merge bool) error {
 	m.rw.Lock()
 	defer m.rw.Unlock()
 
 	if m.root == nil {
-		m.root = &Node{Name: "/", IsDir: true}
+		m.root = &Node{Name: "/", IsDir: true, Mutex: &sync.RWMutex{}}
+	}
+
+	if resource.FS != nil {
+		return m.load(resource.FS, merge)
 	}
 
 	newReader := zipexe.NewReader
@@ -145,31 +339,355 @@ func (m *ResourceManager) Add(resource *Resource) error {
 		return err
 	}
 
-	return m.uncompress(reader)
+	registerDecompressors(reader)
+
+	if m.PublicKey != nil {
+		if err := verify(reader, m.PublicKey); err != nil {
+			return err
+		}
+	}
+
+	assets, err := readManifest(reader)
+	if err != nil {
+		return err
+	}
+
+	conflict, err := m.uncompress(reader, merge)
+	if err != nil {
+		return err
+	}
+
+	m.bundles = append(m.bundles, reader)
+	m.manifest = m.manifest.merge(assets, conflict)
+
+	if conflict != nil {
+		return conflict
+	}
+
+	return nil
 }
 
-func (m *ResourceManager) uncompress(reader *zip.Reader) error {
+// Verify verifies the signatures of the added bundles against given public
+// key. The bundles that are added afterwards are verified before they are
+// loaded. If the verification fails, the manager (and its namespaces and
+// sub-managers) refuse to open any resource from then on.
+//
+// Only the zip bundles are signed. The file systems added by AddFS (for
+// instance embed.FS) are compiled into the executable, so they are not
+// verified and Verify succeeds if the manager does not have any bundle.
+func (m *ResourceManager) Verify(key ed25519.PublicKey) error {
+	if err := m.verify(key); err != nil {
+		return err
+	}
+
+	for _, namespace := range registry.linked(m) {
+		if err := namespace.verify(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verify verifies the bundles of the manager. The failure is reported by
+// Err as well.
+func (m *ResourceManager) verify(key ed25519.PublicKey) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	m.PublicKey = key
+
+	for _, bundle := range m.bundles {
+		if err := verify(bundle, key); err != nil {
+			m.health().refuse(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *ResourceManager) publicKey() ed25519.PublicKey {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	return m.PublicKey
+}
+
+// readManifest reads the manifest of the fingerprinted resources if the
+// bundle has one. The manifest is not a resource, so it is not added to the
+// file tree.
+func readManifest(reader *zip.Reader) (manifest, error) {
 	for _, header := range reader.File {
+		if header.Name != ManifestName {
+			continue
+		}
+
+		source := &zipSource{file: header, hash: decodeMetadata(header.Comment).Get(metadataHash)}
+
+		file, err := source.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+
+		if err != nil {
+			return nil, &EntryError{Name: ManifestName, Err: err}
+		}
+
+		items, err := decodeManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %v", err)
+		}
+
+		return items, nil
+	}
+
+	return nil, nil
+}
+
+func (m *ResourceManager) uncompress(reader *zip.Reader, merge bool) (*ConflictError, error) {
+	names := []string{}
+
+	for _, header := range reader.File {
+		if header.Name != ManifestName {
+			names = append(names, header.Name)
+		}
+	}
+
+	conflict := m.conflict(names)
+	if conflict != nil && !merge {
+		return nil, conflict
+	}
+
+	// the entries are added to a scratch tree, which is grafted onto the
+	// root only if all of them are valid
+	scratch := &Node{Name: "/", IsDir: true, Mutex: &sync.RWMutex{}}
+
+	for _, header := range reader.File {
+		if header.Name == ManifestName || conflict.contains(header.Name) {
+			continue
+		}
+
 		path := split(header.Name)
-		node := add(path, m.root)
+		node := add(path, scratch)
 
-		if node == m.root || node == nil {
-			return fmt.Errorf("invalid path: '%s'", header.Name)
+		if node == scratch || node == nil {
+			return nil, fmt.Errorf("invalid path: '%s'", header.Name)
 		}
 
+		// make sure that the compression algorithm is supported
 		file, err := header.Open()
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		if err = file.Close(); err != nil {
+			return nil, err
+		}
+
+		metadata := decodeMetadata(header.Comment)
+
+		if header.Mode()&os.ModeSymlink != 0 {
+			if node.Link, err = readLink(header); err != nil {
+				return nil, err
+			}
+		}
+
+		node.IsDir = false
+		node.Source = &zipSource{file: header, hash: metadata.Get(metadataHash)}
+		node.Hash = metadata.Get(metadataHash)
+		node.Mode = header.Mode().Perm()
+		node.Metadata = entryMetadata(metadata)
+
+		if !header.Modified.IsZero() {
+			node.ModTime = header.Modified
+		}
+	}
+
+	graft(m.root, scratch)
+	return conflict, nil
+}
+
+// graft moves the children of the source directory to the destination
+// directory. The directories that exist in both of them are merged.
+func graft(dst, src *Node) {
+	for _, child := range src.Children {
+		_, existing := find([]string{child.Name}, nil, dst)
+
+		if existing != nil && existing.IsDir && child.IsDir {
+			graft(existing, child)
+			continue
+		}
+
+		dst.Children = append(dst.Children, child)
+	}
+}
+
+// readLink reads the destination of the symbolic link entry
+func readLink(header *zip.File) (string, error) {
+	reader, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) == 0 {
+		return "", fmt.Errorf("invalid symbolic link: '%s'", header.Name)
+	}
+
+	return string(data), nil
+}
+
+// conflict returns a *ConflictError if any of the paths already exists
+func (m *ResourceManager) conflict(names []string) *ConflictError {
+	paths := []string{}
+
+	for _, name := range names {
+		if occupied(split(name), m.root) {
+			paths = append(paths, name)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return &ConflictError{Paths: paths}
+}
+
+// sectionSource is implemented by the sources that can be read directly
+// without decompression
+type sectionSource interface {
+	Section() (*io.SectionReader, bool)
+}
+
+// zipSource provides the content of a zip entry
+type zipSource struct {
+	file *zip.File
+	hash string
+}
+
+// Name returns the path of the entry
+func (s *zipSource) Name() string {
+	return s.file.Name
+}
+
+// Open opens the entry for read. The checksum of the content is validated
+// once the content is read completely.
+func (s *zipSource) Open() (io.ReadCloser, error) {
+	reader, err := s.file.Open()
+	if err != nil || s.hash == "" {
+		return reader, err
+	}
+
+	return newHashReader(reader, s.hash), nil
+}
+
+// Size returns the uncompressed size of the entry
+func (s *zipSource) Size() int64 {
+	return int64(s.file.UncompressedSize64)
+}
+
+// Section returns a reader of the stored (uncompressed) entry that reads
+// directly from the underlying bundle
+func (s *zipSource) Section() (*io.SectionReader, bool) {
+	if s.file.Method != zip.Store {
+		return nil, false
+	}
+
+	reader, err := s.file.OpenRaw()
+	if err != nil {
+		return nil, false
+	}
+
+	section, ok := reader.(*io.SectionReader)
+	return section, ok
+}
+
+// fsSource provides the content of a file in fs.FS
+type fsSource struct {
+	fileSystem fs.FS
+	path       string
+	size       int64
+}
+
+// Name returns the path of the file
+func (s *fsSource) Name() string {
+	return s.path
+}
+
+// Open opens the file for read
+func (s *fsSource) Open() (io.ReadCloser, error) {
+	return s.fileSystem.Open(s.path)
+}
+
+// Size returns the size of the file
+func (s *fsSource) Size() int64 {
+	return s.size
+}
+
+func (m *ResourceManager) load(fileSystem fs.FS, merge bool) error {
+	files := []string{}
+
+	err := fs.WalkDir(fileSystem, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			files = append(files, path)
 		}
-		defer file.Close()
 
-		content, err := ioutil.ReadAll(file)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	conflict := m.conflict(files)
+	if conflict != nil && !merge {
+		return conflict
+	}
+
+	// the files are added to a scratch tree, which is grafted onto the root
+	// only if all of them are valid
+	scratch := &Node{Name: "/", IsDir: true, Mutex: &sync.RWMutex{}}
+
+	for _, path := range files {
+		if conflict.contains(path) {
+			continue
+		}
+
+		node := add(split(path), scratch)
+
+		if node == scratch || node == nil {
+			return fmt.Errorf("invalid path: '%s'", path)
+		}
+
+		info, err := fs.Stat(fileSystem, path)
 		if err != nil {
 			return err
 		}
 
 		node.IsDir = false
-		node.Content = &content
+		node.Source = &fsSource{fileSystem: fileSystem, path: path, size: info.Size()}
+		node.Mode = info.Mode().Perm()
+
+		if !info.ModTime().IsZero() {
+			node.ModTime = info.ModTime()
+		}
+	}
+
+	graft(m.root, scratch)
+
+	if conflict != nil {
+		return conflict
 	}
 
 	return nil
@@ -177,15 +695,41 @@ func (m *ResourceManager) uncompress(reader *zip.Reader) error {
 
 // Dir returns a sub-manager for given path
 func (m *ResourceManager) Dir(name string) (FileSystemManager, error) {
-	if _, node := find(split(name), nil, m.root); node != nil {
+	parts, err := m.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, node := find(parts, nil, m.root); node != nil {
 		if node.IsDir {
-			return &ResourceManager{root: node}, nil
+			return &ResourceManager{
+				root:      node,
+				manifest:  m.manifest.sub(name),
+				checks:    m.health(),
+				Cache:     m.Cache,
+				PublicKey: m.PublicKey,
+			}, nil
 		}
 	}
 
 	return nil, os.ErrNotExist
 }
 
+// AssetPath returns the fingerprinted path of the named resource. The path is
+// looked up in the manifest of the bundle, if the bundle does not have one,
+// it is computed from the content hash.
+func (m *ResourceManager) AssetPath(name string) (string, error) {
+	m.rw.RLock()
+	value, ok := m.manifest.lookup(name)
+	m.rw.RUnlock()
+
+	if ok {
+		return value, nil
+	}
+
+	return Fingerprint(m, name)
+}
+
 // Open opens an embedded resource for read
 func (m *ResourceManager) Open(name string) (ReadOnlyFile, error) {
 	return m.OpenFile(name, os.O_RDONLY, 0)
@@ -208,17 +752,23 @@ func (m *ResourceManager) OpenFile(name string, flag int, perm os.FileMode) (Fil
 		}
 
 		node = newNode(filepath.Base(name), parent)
+		node.Mode = perm.Perm()
 	}
 
 	if node == nil {
 		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
 	}
 
-	return newFile(node, flag)
+	return m.newFile(node, flag)
 }
 
 func (m *ResourceManager) open(name string) (*Node, *Node, error) {
-	parent, node := find(split(name), nil, m.root)
+	parts, err := m.resolve(name, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parent, node := find(parts, nil, m.root)
 	if node != m.root && parent == nil {
 		return nil, nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
 	}
@@ -229,13 +779,72 @@ func (m *ResourceManager) open(name string) (*Node, *Node, error) {
 // Walk walks the file tree rooted at root, calling walkFn for each file or
 // directory in the tree, including root.
 func (m *ResourceManager) Walk(dir string, fn filepath.WalkFunc) error {
-	if _, node := find(split(dir), nil, m.root); node != nil {
+	parts, err := m.resolve(dir, true)
+	if err != nil {
+		return err
+	}
+
+	if _, node := find(parts, nil, m.root); node != nil {
 		return walk(dir, node, fn)
 	}
 
 	return os.ErrNotExist
 }
 
+// Stat returns a FileInfo describing the named file
+func (m *ResourceManager) Stat(name string) (os.FileInfo, error) {
+	_, node, err := m.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node == nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return &ResourceFileInfo{Node: node}, nil
+}
+
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename
+func (m *ResourceManager) ReadDir(name string) ([]fs.DirEntry, error) {
+	_, node, err := m.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node == nil {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+
+	if !node.IsDir {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: ErrNotDirectory}
+	}
+
+	return dirEntries(children(node)), nil
+}
+
+// ReadFile reads the named file and returns its contents
+func (m *ResourceManager) ReadFile(name string) ([]byte, error) {
+	file, err := m.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: name, Err: ErrIsDirectory}
+	}
+
+	return ioutil.ReadAll(file)
+}
+
 func add(path []string, node *Node) *Node {
 	if !node.IsDir || node.Content != nil {
 		return nil
@@ -264,6 +873,26 @@ func add(path []string, node *Node) *Node {
 	return add(path[1:], child)
 }
 
+// occupied returns true if the path or any of its parent directories
+// already exists as a file
+func occupied(path []string, node *Node) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	for _, child := range node.Children {
+		if child.Name == path[0] {
+			if len(path) == 1 || !child.IsDir {
+				return true
+			}
+
+			return occupied(path[1:], child)
+		}
+	}
+
+	return false
+}
+
 func split(path string) []string {
 	parts := []string{}
 
@@ -307,10 +936,25 @@ func walk(path string, node *Node, fn filepath.WalkFunc) error {
 	return nil
 }
 
+func children(node *Node) []os.FileInfo {
+	info := []os.FileInfo{}
+
+	for _, child := range node.Children {
+		info = append(info, &ResourceFileInfo{Node: child})
+	}
+
+	sort.Slice(info, func(i, j int) bool {
+		return info[i].Name() < info[j].Name()
+	})
+
+	return info
+}
+
 func newNode(name string, parent *Node) *Node {
 	node := &Node{
 		Name:    name,
 		IsDir:   false,
+		Mutex:   &sync.RWMutex{},
 		ModTime: time.Now(),
 	}
 
@@ -318,18 +962,33 @@ func newNode(name string, parent *Node) *Node {
 	return node
 }
 
-func newFile(node *Node, flag int) (File, error) {
+func (m *ResourceManager) newFile(node *Node, flag int) (File, error) {
 	if isWritable(flag) {
+		node.Mutex.Lock()
 		node.ModTime = time.Now()
+		node.Mutex.Unlock()
 	}
 
-	if node.Content == nil || hasFlag(os.O_TRUNC, flag) {
-		buf := make([]byte, 0)
-		node.Content = &buf
-		node.Mutex = &sync.RWMutex{}
+	if hasFlag(os.O_TRUNC, flag) {
+		m.truncate(node)
 	}
 
-	f := NewResourceFile(node)
+	if !isWritable(flag) {
+		if section, source, ok := m.section(node); ok {
+			if err := m.validate(node, source); err != nil {
+				return nil, &os.PathError{Op: "open", Path: node.Name, Err: err}
+			}
+
+			return &sectionFile{SectionReader: section, node: node}, nil
+		}
+	}
+
+	content, err := m.content(node, isWritable(flag))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: node.Name, Err: err}
+	}
+
+	f := newResourceFile(node, content)
 
 	if hasFlag(os.O_APPEND, flag) {
 		_, _ = f.Seek(0, io.SeekEnd)
@@ -345,6 +1004,143 @@ func newFile(node *Node, flag int) (File, error) {
 	return &roFile{f}, nil
 }
 
+// content returns the content of the node. The content of lazy nodes is
+// decompressed and kept in the cache. If the content will be modified, it
+// is attached to the node permanently.
+func (m *ResourceManager) content(node *Node, writable bool) (*[]byte, error) {
+	node.Mutex.RLock()
+	content, source := node.Content, node.Source
+	node.Mutex.RUnlock()
+
+	if content != nil {
+		return content, nil
+	}
+
+	if source == nil {
+		m.truncate(node)
+		return node.Content, nil
+	}
+
+	data, err := m.decompress(node, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if !writable {
+		// the content may be shared by the cache, so the read-only view
+		// cannot grow into its spare capacity
+		data = data[:len(data):len(data)]
+		return &data, nil
+	}
+
+	node.Mutex.Lock()
+	defer node.Mutex.Unlock()
+
+	if node.Content == nil {
+		// the cached content cannot be shared once it becomes writable
+		buffer := make([]byte, len(data))
+		copy(buffer, data)
+
+		node.Content = &buffer
+		node.Source = nil
+		node.Hash = ""
+	}
+
+	if m.Cache != nil {
+		m.Cache.Remove(node)
+	}
+
+	return node.Content, nil
+}
+
+// section returns a reader of the node content if it is stored without
+// compression in the underlying bundle
+func (m *ResourceManager) section(node *Node) (*io.SectionReader, Source, bool) {
+	if node.Mutex == nil {
+		return nil, nil, false
+	}
+
+	node.Mutex.RLock()
+	content, source := node.Content, node.Source
+	node.Mutex.RUnlock()
+
+	if content != nil {
+		return nil, nil, false
+	}
+
+	if section, ok := source.(sectionSource); ok {
+		if reader, ok := section.Section(); ok {
+			return reader, source, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// validate validates the content of the node, unless it has been validated
+// already. The invalid entries are reported by Err.
+func (m *ResourceManager) validate(node *Node, source Source) error {
+	health := m.health()
+
+	if err, ok := health.result(node); ok {
+		return err
+	}
+
+	reader, err := source.Open()
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, reader)
+		reader.Close()
+	}
+
+	return health.record(node, source, err)
+}
+
+func (m *ResourceManager) decompress(node *Node, source Source) ([]byte, error) {
+	health := m.health()
+
+	if err, ok := health.result(node); ok && err != nil {
+		return nil, err
+	}
+
+	if m.Cache != nil {
+		if data, ok := m.Cache.Get(node); ok {
+			return data, nil
+		}
+	}
+
+	reader, err := source.Open()
+	if err != nil {
+		return nil, health.record(node, source, err)
+	}
+
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err = health.record(node, source, err); err != nil {
+		return nil, err
+	}
+
+	if m.Cache != nil {
+		m.Cache.Put(node, data)
+	}
+
+	return data, nil
+}
+
+func (m *ResourceManager) truncate(node *Node) {
+	node.Mutex.Lock()
+	defer node.Mutex.Unlock()
+
+	buffer := make([]byte, 0)
+	node.Content = &buffer
+	node.Source = nil
+	node.Hash = ""
+
+	if m.Cache != nil {
+		m.Cache.Remove(node)
+	}
+}
+
 func hasFlag(flag int, flags int) bool {
 	return flags&flag == flag
 }
@@ -362,6 +1158,12 @@ func (f *roFile) Write(p []byte) (n int, err error) {
 	return 0, ErrReadOnly
 }
 
+// Truncate is disabled and returns ErrorReadOnly, because the content may be
+// shared with the other readers
+func (f *roFile) Truncate(size int64) error {
+	return ErrReadOnly
+}
+
 // woFile wraps the given file and disables Read(..) operation.
 type woFile struct {
 	*ResourceFile
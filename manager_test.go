@@ -2,11 +2,20 @@ package parcello_test
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
+	"testing/fstest"
 	"time"
 
 	zipexe "github.com/daaku/go.zipexe"
@@ -98,12 +107,171 @@ var _ = Describe("ResourceManager", func() {
 				Expect(err).To(MatchError("oh no!"))
 			})
 		})
+
+		Context("when the bundle is not signed by the public key", func() {
+			It("returns an error", func() {
+				dir, err := ioutil.TempDir("", "parcello")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(dir)
+
+				Expect(ioutil.WriteFile(filepath.Join(dir, "binary"), bundle.Body, 0600)).To(Succeed())
+
+				key, _, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).NotTo(HaveOccurred())
+
+				cfg := &parcello.ResourceManagerConfig{
+					Path:       "binary",
+					FileSystem: parcello.Dir(dir),
+					PublicKey:  key,
+				}
+
+				m, err := parcello.NewResourceManager(cfg)
+				Expect(m).To(BeNil())
+				Expect(err).To(Equal(parcello.ErrUnsigned))
+			})
+		})
 	})
 
 	Describe("Add", func() {
 		Context("when the resource is added second time", func() {
 			It("returns an error", func() {
-				Expect(manager.Add(resource)).To(MatchError("invalid path: 'resource/reports/2018.txt'"))
+				err := manager.Add(resource)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(HavePrefix("Conflicting resources 'resource/reports/2018.txt'"))
+
+				conflict, ok := err.(*parcello.ConflictError)
+				Expect(ok).To(BeTrue())
+				Expect(conflict.Paths).To(ContainElement("resource/reports/2018.txt"))
+			})
+		})
+
+		Context("when the bundle has an invalid path", func() {
+			It("does not add any of the entries", func() {
+				buffer := &bytes.Buffer{}
+				writer := zip.NewWriter(buffer)
+
+				for _, name := range []string{"resource/docs/README.md", "database/schema.sql", "database/schema.sql/users.sql"} {
+					file, err := writer.Create(name)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = file.Write([]byte("content"))
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				Expect(writer.Close()).To(Succeed())
+
+				err := manager.Add(parcello.BinaryResource(buffer.Bytes()))
+				Expect(err).To(MatchError("invalid path: 'database/schema.sql/users.sql'"))
+
+				_, err = manager.Open("/database")
+				Expect(os.IsNotExist(err)).To(BeTrue())
+
+				_, err = manager.Open("/resource/docs")
+				Expect(os.IsNotExist(err)).To(BeTrue())
+
+				_, err = manager.Open("/resource/reports/2018.txt")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when the bundle has new files in an existing directory", func() {
+			It("merges the directories", func() {
+				buffer := &bytes.Buffer{}
+				writer := zip.NewWriter(buffer)
+
+				file, err := writer.Create("resource/reports/2019.txt")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = file.Write([]byte("Report 2019"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(writer.Close()).To(Succeed())
+
+				Expect(manager.Add(parcello.BinaryResource(buffer.Bytes()))).To(Succeed())
+
+				dir, err := manager.Open("/resource/reports")
+				Expect(err).NotTo(HaveOccurred())
+
+				files, err := dir.Readdir(-1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(files).To(HaveLen(2))
+			})
+		})
+
+		It("does not decompress the resources", func() {
+			file, err := manager.Open("/resource/reports")
+			Expect(err).NotTo(HaveOccurred())
+
+			files, err := file.Readdir(-1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+
+			info := files[0].(*parcello.ResourceFileInfo)
+			Expect(info.Node.Content).To(BeNil())
+			Expect(info.Node.Source).NotTo(BeNil())
+			Expect(info.Size()).To(Equal(int64(len("Report 2018\n"))))
+		})
+
+		Context("when the resource is file system", func() {
+			It("adds the files", func() {
+				fileSystem := fstest.MapFS{
+					"database/schema.sql": &fstest.MapFile{Data: []byte("CREATE TABLE users;")},
+				}
+
+				Expect(manager.Add(parcello.FSResource(fileSystem))).To(Succeed())
+
+				file, err := manager.Open("/database/schema.sql")
+				Expect(err).NotTo(HaveOccurred())
+
+				data, err := ioutil.ReadAll(file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("CREATE TABLE users;"))
+			})
+
+			Context("when the file already exists", func() {
+				It("returns an error", func() {
+					fileSystem := fstest.MapFS{
+						"resource/reports/2018.txt": &fstest.MapFile{Data: []byte("Report")},
+					}
+
+					Expect(manager.Add(parcello.FSResource(fileSystem))).To(MatchError("Conflicting resources 'resource/reports/2018.txt'"))
+				})
+
+				It("does not add any of the files", func() {
+					fileSystem := fstest.MapFS{
+						"database/schema.sql":       &fstest.MapFile{Data: []byte("CREATE TABLE users;")},
+						"resource/reports/2018.txt": &fstest.MapFile{Data: []byte("Report")},
+					}
+
+					Expect(manager.Add(parcello.FSResource(fileSystem))).To(HaveOccurred())
+
+					_, err := manager.Open("/database/schema.sql")
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				})
+			})
+
+			Context("when a parent directory is a file", func() {
+				It("returns an error", func() {
+					fileSystem := fstest.MapFS{
+						"resource/reports/2018.txt/summary.txt": &fstest.MapFile{Data: []byte("Summary")},
+					}
+
+					Expect(manager.Add(parcello.FSResource(fileSystem))).To(MatchError("Conflicting resources 'resource/reports/2018.txt/summary.txt'"))
+				})
+			})
+
+			Context("when a file cannot be stat", func() {
+				It("does not add any of the files", func() {
+					fileSystem := &unstatFS{
+						FS: fstest.MapFS{
+							"database/schema.sql": &fstest.MapFile{Data: []byte("CREATE TABLE users;")},
+							"database/users.sql":  &fstest.MapFile{Data: []byte("INSERT INTO users;")},
+						},
+						name: "database/users.sql",
+					}
+
+					Expect(manager.Add(parcello.FSResource(fileSystem))).To(MatchError("oh no!"))
+
+					_, err := manager.Open("/database/schema.sql")
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				})
 			})
 		})
 
@@ -138,8 +306,12 @@ var _ = Describe("ResourceManager", func() {
 				}
 			})
 
-			It("returns an error", func() {
-				Expect(manager.Add(resource)).To(MatchError("zip: checksum error"))
+			It("returns an error when the file is opened", func() {
+				Expect(manager.Add(resource)).To(Succeed())
+
+				file, err := manager.Open("/resource/reports/2018.txt")
+				Expect(file).To(BeNil())
+				Expect(err).To(MatchError("open 2018.txt: zip: checksum error"))
 			})
 		})
 
@@ -248,6 +420,172 @@ var _ = Describe("ResourceManager", func() {
 			Expect(string(data)).To(Equal("Report 2018\n"))
 		})
 
+		It("returns a seekable resource", func() {
+			file, err := manager.Open("/resource/reports/2018.txt")
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := file.Stat()
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest("GET", "/2018.txt", nil)
+			request.Header.Set("Range", "bytes=7-10")
+
+			http.ServeContent(recorder, request, info.Name(), info.ModTime(), file)
+			Expect(recorder.Code).To(Equal(http.StatusPartialContent))
+			Expect(recorder.Body.String()).To(Equal("2018"))
+
+			data := make([]byte, 6)
+			_, err = file.(io.ReaderAt).ReadAt(data, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("Report"))
+		})
+
+		Context("when the resource is stored without compression", func() {
+			BeforeEach(func() {
+				compressor := parcello.ZipCompressor{
+					Config: &parcello.CompressorConfig{
+						Logger:   ioutil.Discard,
+						Filename: "bundle",
+						Recurive: true,
+						Methods: []parcello.CompressionMethod{
+							{Pattern: "*.txt", Method: zip.Store},
+						},
+					},
+				}
+
+				var err error
+
+				bundle, err = compressor.Compress(&parcello.CompressorContext{
+					FileSystem: parcello.Dir("./fixture"),
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("reads the resource directly from the bundle", func() {
+				file, err := manager.Open("/resource/reports/2018.txt")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file).NotTo(BeAssignableToTypeOf(&parcello.ResourceFile{}))
+
+				info, err := file.Stat()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Name()).To(Equal("2018.txt"))
+				Expect(info.Size()).To(Equal(int64(len("Report 2018\n"))))
+				Expect(info.(*parcello.ResourceFileInfo).Node.Content).To(BeNil())
+
+				data, err := ioutil.ReadAll(file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("Report 2018\n"))
+
+				_, err = fmt.Fprintln(file.(io.Writer), "hello")
+				Expect(err).To(MatchError("File is read-only"))
+			})
+
+			Context("when the resource is open for write", func() {
+				It("copies the content", func() {
+					file, err := manager.OpenFile("/resource/reports/2018.txt", os.O_RDWR|os.O_APPEND, 0600)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = fmt.Fprint(file, "hello")
+					Expect(err).NotTo(HaveOccurred())
+
+					reader, err := manager.Open("/resource/reports/2018.txt")
+					Expect(err).NotTo(HaveOccurred())
+
+					data, err := ioutil.ReadAll(reader)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(data)).To(Equal("Report 2018\nhello"))
+				})
+			})
+		})
+
+		Context("when the cache is enabled", func() {
+			BeforeEach(func() {
+				manager.Cache = parcello.NewContentCache(1024)
+			})
+
+			It("keeps the decompressed content in the cache", func() {
+				file, err := manager.Open("/resource/reports/2018.txt")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(manager.Cache.Size()).To(Equal(int64(len("Report 2018\n"))))
+
+				info, err := file.Stat()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.(*parcello.ResourceFileInfo).Node.Content).To(BeNil())
+
+				data, err := ioutil.ReadAll(file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("Report 2018\n"))
+			})
+
+			Context("when the resource is opened concurrently", func() {
+				It("shares the content safely", func() {
+					var group sync.WaitGroup
+
+					for index := 0; index < 16; index++ {
+						group.Add(1)
+
+						go func(index int) {
+							defer GinkgoRecover()
+							defer group.Done()
+
+							if index%4 == 0 {
+								file, err := manager.OpenFile("/resource/scripts/schema.sql", os.O_RDWR, 0600)
+								Expect(err).NotTo(HaveOccurred())
+
+								_, err = fmt.Fprint(file, "changed")
+								Expect(err).NotTo(HaveOccurred())
+								Expect(file.Close()).To(Succeed())
+								return
+							}
+
+							data, err := manager.ReadFile("/resource/reports/2018.txt")
+							Expect(err).NotTo(HaveOccurred())
+							Expect(string(data)).To(Equal("Report 2018\n"))
+						}(index)
+					}
+
+					group.Wait()
+				})
+			})
+
+			Context("when a read-only file is truncated", func() {
+				It("does not change the cached content", func() {
+					file, err := manager.Open("/resource/reports/2018.txt")
+					Expect(err).NotTo(HaveOccurred())
+
+					truncater, ok := file.(interface{ Truncate(int64) error })
+					Expect(ok).To(BeTrue())
+					Expect(truncater.Truncate(64)).To(MatchError(parcello.ErrReadOnly))
+
+					data, err := manager.ReadFile("/resource/reports/2018.txt")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(data)).To(Equal("Report 2018\n"))
+				})
+			})
+
+			Context("when the resource is modified", func() {
+				It("removes the content from the cache", func() {
+					_, err := manager.Open("/resource/reports/2018.txt")
+					Expect(err).NotTo(HaveOccurred())
+
+					file, err := manager.OpenFile("/resource/reports/2018.txt", os.O_RDWR|os.O_APPEND, 0600)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(manager.Cache.Size()).To(BeZero())
+
+					_, err = fmt.Fprint(file, "hello")
+					Expect(err).NotTo(HaveOccurred())
+
+					reader, err := manager.Open("/resource/reports/2018.txt")
+					Expect(err).NotTo(HaveOccurred())
+
+					data, err := ioutil.ReadAll(reader)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(data)).To(Equal("Report 2018\nhello"))
+				})
+			})
+		})
+
 		Context("when the file is open more than once for read", func() {
 			It("does not change the mod time", func() {
 				file, err := manager.Open("/resource/reports/2018.txt")
@@ -371,6 +709,59 @@ var _ = Describe("ResourceManager", func() {
 		})
 	})
 
+	Describe("Stat", func() {
+		BeforeEach(func() {
+			modTime := time.Date(2020, time.May, 4, 10, 30, 0, 0, time.UTC)
+
+			compressor := parcello.ZipCompressor{
+				Config: &parcello.CompressorConfig{
+					Logger:   ioutil.Discard,
+					Filename: "bundle",
+					Recurive: true,
+					Metadata: func(path string, info os.FileInfo) parcello.Metadata {
+						return parcello.Metadata{"owner": "ops", "sha256": "forged"}
+					},
+				},
+			}
+
+			var err error
+
+			bundle, err = compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.FromFS(fstest.MapFS{
+					"bin/migrate.sh": &fstest.MapFile{Data: []byte("#!/bin/sh"), Mode: 0755, ModTime: modTime},
+					"sql/schema.sql": &fstest.MapFile{Data: []byte("CREATE TABLE users;"), Mode: 0600, ModTime: modTime},
+				}),
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the mode of the resource", func() {
+			info, err := manager.Stat("bin/migrate.sh")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode()).To(Equal(os.FileMode(0755)))
+			Expect(info.ModTime().Equal(time.Date(2020, time.May, 4, 10, 30, 0, 0, time.UTC))).To(BeTrue())
+
+			info, err = manager.Stat("sql/schema.sql")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("returns the mode of the directory", func() {
+			info, err := manager.Stat("bin")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		})
+
+		It("returns the metadata of the resource", func() {
+			info, err := manager.Stat("bin/migrate.sh")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Sys()).To(HaveKeyWithValue("owner", "ops"))
+			Expect(parcello.MetadataOf(info).Get("owner")).To(Equal("ops"))
+			Expect(info.(*parcello.ResourceFileInfo).Hash()).NotTo(Equal("forged"))
+		})
+	})
+
 	Describe("Walk", func() {
 		Context("when the resource is empty", func() {
 			It("returns an error", func() {
@@ -473,17 +864,69 @@ var _ = Describe("DefaultManager", func() {
 		Expect(ok).To(BeTrue())
 	})
 
+	It("does not report errors", func() {
+		manager := parcello.DefaultManager(osext.Executable)
+		Expect(manager.(*parcello.ResourceManager).Err()).To(Succeed())
+	})
+
 	Context("when the executable cannot be found", func() {
-		It("panics", func() {
+		It("reports the error", func() {
+			fn := func() (string, error) { return "", fmt.Errorf("oh no!") }
+
+			manager := parcello.DefaultManager(fn)
+			Expect(manager).NotTo(BeNil())
+
+			err := manager.(*parcello.ResourceManager).Err()
+			Expect(err).To(BeAssignableToTypeOf(&parcello.BundleError{}))
+			Expect(err).To(MatchError("Invalid bundle: oh no!"))
+		})
+
+		It("returns an empty root directory", func() {
 			fn := func() (string, error) { return "", fmt.Errorf("oh no!") }
-			Expect(func() { parcello.DefaultManager(fn) }).To(Panic())
+
+			manager := parcello.DefaultManager(fn)
+
+			dir, err := manager.Dir("/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dir).NotTo(BeNil())
+
+			file, err := manager.OpenFile("/a.txt", os.O_CREATE|os.O_RDWR, 0600)
+			Expect(file).To(BeNil())
+			Expect(os.IsNotExist(err)).To(BeTrue())
 		})
 	})
 
 	Context("when the filesystem fails", func() {
-		It("panics", func() {
+		It("reports the error", func() {
 			fn := func() (string, error) { return "/i/do/not/exist", nil }
-			Expect(func() { parcello.DefaultManager(fn) }).To(Panic())
+
+			manager := parcello.DefaultManager(fn)
+			Expect(manager).NotTo(BeNil())
+
+			err := manager.(*parcello.ResourceManager).Err()
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
+
+	Context("when the overlay directories are provided", func() {
+		BeforeEach(func() {
+			os.Setenv("PARCELLO_OVERLAY_DIR", "./patch"+string(os.PathListSeparator)+"./override")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("PARCELLO_OVERLAY_DIR")
+		})
+
+		It("stacks the directories over the resource manager", func() {
+			manager := parcello.DefaultManager(osext.Executable)
+			Expect(manager).NotTo(BeNil())
+
+			overlay, ok := manager.(*parcello.Overlay)
+			Expect(ok).To(BeTrue())
+			Expect(overlay.Layers).To(HaveLen(3))
+			Expect(overlay.Layers[0]).To(Equal(parcello.Dir("./patch")))
+			Expect(overlay.Layers[1]).To(Equal(parcello.Dir("./override")))
+			Expect(overlay.Layers[2]).To(BeAssignableToTypeOf(&parcello.ResourceManager{}))
 		})
 	})
 
@@ -523,3 +966,16 @@ var _ = Describe("DefaultManager", func() {
 		})
 	})
 })
+
+type unstatFS struct {
+	fs.FS
+	name string
+}
+
+func (f *unstatFS) Open(name string) (fs.File, error) {
+	if name == f.name {
+		return nil, fmt.Errorf("oh no!")
+	}
+
+	return f.FS.Open(name)
+}
@@ -0,0 +1,83 @@
+package parcello
+
+import (
+	"net/url"
+	"os"
+)
+
+const (
+	// metadataHash is the key of the SHA-256 hash of the content
+	metadataHash = "sha256"
+	// MetadataContentType is the metadata key of the media type of the content
+	MetadataContentType = "content-type"
+)
+
+// Metadata contains the attributes of a bundled resource (for instance its
+// content type) as key/value pairs
+type Metadata map[string]string
+
+// MetadataFunc returns the metadata of the resource that is bundled under
+// given path. It is called by the compressor for every resource.
+type MetadataFunc func(path string, info os.FileInfo) Metadata
+
+// MetadataOf returns the metadata of the resource described by the file info.
+// It is empty if the resource has not been bundled with any.
+func MetadataOf(info os.FileInfo) Metadata {
+	if metadata, ok := info.Sys().(Metadata); ok {
+		return metadata
+	}
+
+	return Metadata{}
+}
+
+// Get returns the value associated with the key
+func (m Metadata) Get(key string) string {
+	return m[key]
+}
+
+// ContentType returns the media type of the resource
+func (m Metadata) ContentType() string {
+	return m.Get(MetadataContentType)
+}
+
+// encodeMetadata returns the comment of a zip entry that stores the metadata
+// of the resource as URL encoded values
+func encodeMetadata(values url.Values) string {
+	return values.Encode()
+}
+
+// decodeMetadata returns the metadata values stored in the comment of a zip
+// entry. The comments that are not URL encoded values have no metadata.
+func decodeMetadata(comment string) url.Values {
+	values, err := url.ParseQuery(comment)
+	if err != nil {
+		return url.Values{}
+	}
+
+	return values
+}
+
+// entryMetadata returns the metadata from the values of an entry comment
+// without the reserved values
+func entryMetadata(values url.Values) Metadata {
+	metadata := Metadata{}
+
+	for key := range values {
+		if key != metadataHash {
+			metadata[key] = values.Get(key)
+		}
+	}
+
+	return metadata
+}
+
+// values returns the metadata as URL values
+func (m Metadata) values() url.Values {
+	values := url.Values{}
+
+	for key, value := range m {
+		values.Set(key, value)
+	}
+
+	return values
+}
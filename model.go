@@ -2,8 +2,10 @@ package parcello
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -38,6 +40,11 @@ type FileSystemManager interface {
 	Dir(name string) (FileSystemManager, error)
 	// Add resource bundle to the manager
 	Add(resource *Resource) error
+	// AssetPath returns the fingerprinted path of the named resource
+	AssetPath(name string) (string, error)
+	// Watch delivers the changes of the resources that match the pattern
+	// until the context is done
+	Watch(ctx context.Context, pattern string) (<-chan Event, error)
 }
 
 // Resource represents a resource
@@ -46,6 +53,8 @@ type Resource struct {
 	Body io.ReaderAt
 	// Size of the body
 	Size int64
+	// FS contains the resource files (if it is set, the body is ignored)
+	FS fs.FS
 }
 
 // BinaryResource creates a binary resource
@@ -56,6 +65,13 @@ func BinaryResource(data []byte) *Resource {
 	}
 }
 
+// FSResource creates a resource from file system (for instance embed.FS)
+func FSResource(fileSystem fs.FS) *Resource {
+	return &Resource{
+		FS: fileSystem,
+	}
+}
+
 // ReadOnlyFile is the bundle file
 type ReadOnlyFile = http.File
 
@@ -105,6 +121,19 @@ type Bundle struct {
 	Count int
 	// Body of the resource
 	Body []byte
+	// Files contains the paths of the bundled files
+	Files []string
+	// Fingerprint identifies the inputs of the bundle (the resources and the
+	// options of the compression)
+	Fingerprint string
+}
+
+// Source provides the content of a node on demand
+type Source interface {
+	// Open opens the content for read
+	Open() (io.ReadCloser, error)
+	// Size returns the length of the content in bytes
+	Size() int64
 }
 
 // Node represents a node in resource tree
@@ -117,8 +146,21 @@ type Node struct {
 	Mutex *sync.RWMutex
 	// ModTime returns the last modified time
 	ModTime time.Time
+	// Mode contains the permission bits of the node (the defaults are used
+	// if they are not set)
+	Mode os.FileMode
+	// Metadata contains the attributes of the node set by the compressor
+	Metadata Metadata
 	// Content of the node
 	Content *[]byte
+	// Source provides the content of the node on demand (when it is not
+	// loaded in memory)
+	Source Source
+	// Hash is the hex encoded SHA-256 hash of the bundled content
+	Hash string
+	// Link is the destination of the symbolic link (empty if the node is not
+	// a symbolic link)
+	Link string
 	// Children of the node
 	Children []*Node
 }
@@ -143,17 +185,54 @@ func (n *ResourceFileInfo) Size() int64 {
 
 	n.Node.Mutex.RLock()
 	defer n.Node.Mutex.RUnlock()
+
+	if n.Node.Content == nil {
+		if n.Node.Source != nil {
+			return n.Node.Source.Size()
+		}
+
+		return 0
+	}
+
 	l := len(*(n.Node.Content))
 	return int64(l)
 }
 
 // Mode returns the file mode bits
 func (n *ResourceFileInfo) Mode() os.FileMode {
-	return 0
+	mode := n.Node.Mode.Perm()
+
+	switch {
+	case n.Node.IsDir:
+		if mode == 0 {
+			mode = 0755
+		}
+
+		return os.ModeDir | mode
+	case n.Node.Link != "":
+		if mode == 0 {
+			mode = 0777
+		}
+
+		return os.ModeSymlink | mode
+	default:
+		if mode == 0 {
+			mode = 0644
+		}
+
+		return mode
+	}
 }
 
 // ModTime returns the modification time
 func (n *ResourceFileInfo) ModTime() time.Time {
+	if n.Node.Mutex == nil {
+		return n.Node.ModTime
+	}
+
+	n.Node.Mutex.RLock()
+	defer n.Node.Mutex.RUnlock()
+
 	return n.Node.ModTime
 }
 
@@ -162,9 +241,31 @@ func (n *ResourceFileInfo) IsDir() bool {
 	return n.Node.IsDir
 }
 
-// Sys returns the underlying data source
+// Sys returns the Metadata of the node
 func (n *ResourceFileInfo) Sys() interface{} {
-	return nil
+	return n.Metadata()
+}
+
+// Metadata returns the attributes of the node set by the compressor
+func (n *ResourceFileInfo) Metadata() Metadata {
+	if n.Node.Metadata == nil {
+		return Metadata{}
+	}
+
+	return n.Node.Metadata
+}
+
+// Hash returns the hex encoded SHA-256 hash of the bundled content. It is
+// empty for directories and for files that have been modified.
+func (n *ResourceFileInfo) Hash() string {
+	if n.Node.Mutex == nil {
+		return n.Node.Hash
+	}
+
+	n.Node.Mutex.RLock()
+	defer n.Node.Mutex.RUnlock()
+
+	return n.Node.Hash
 }
 
 var _ File = &ResourceFile{}
@@ -172,13 +273,18 @@ var _ File = &ResourceFile{}
 // ResourceFile represents a *bytes.Buffer that can be closed
 type ResourceFile struct {
 	*memfs.MemFile
-	node *Node
+	node   *Node
+	offset int
 }
 
 // NewResourceFile creates a new Buffer
 func NewResourceFile(node *Node) *ResourceFile {
+	return newResourceFile(node, node.Content)
+}
+
+func newResourceFile(node *Node, content *[]byte) *ResourceFile {
 	return &ResourceFile{
-		MemFile: memfs.NewMemFile(node.Name, node.Mutex, node.Content),
+		MemFile: memfs.NewMemFile(node.Name, node.Mutex, content),
 		node:    node,
 	}
 }
@@ -203,11 +309,68 @@ func (b *ResourceFile) Readdir(n int) ([]os.FileInfo, error) {
 	return info, nil
 }
 
+// ReadDir reads the contents of the directory associated with the file and
+// returns a slice of up to n directory entries sorted by filename. Subsequent
+// calls return the next entries, as described by fs.ReadDirFile.
+func (b *ResourceFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !b.node.IsDir {
+		return nil, &os.PathError{Op: "readdir", Path: b.node.Name, Err: ErrNotDirectory}
+	}
+
+	info := children(b.node)
+	info = info[b.offset:]
+
+	if n > 0 && len(info) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > 0 && len(info) > n {
+		info = info[:n]
+	}
+
+	b.offset = b.offset + len(info)
+	return dirEntries(info), nil
+}
+
 // Stat returns the FileInfo structure describing file.
 // If there is an error, it will be of type *PathError.
 func (b *ResourceFile) Stat() (os.FileInfo, error) {
 	return &ResourceFileInfo{Node: b.node}, nil
 }
 
+var _ File = &sectionFile{}
+
+// sectionFile is a read-only file that reads its content directly from the
+// underlying bundle without copying it
+type sectionFile struct {
+	*io.SectionReader
+	node *Node
+}
+
+// Close closes the file
+func (f *sectionFile) Close() error {
+	return nil
+}
+
+// Write is disabled and returns ErrReadOnly
+func (f *sectionFile) Write(p []byte) (n int, err error) {
+	return 0, ErrReadOnly
+}
+
+// Readdir is not supported for regular files
+func (f *sectionFile) Readdir(n int) ([]os.FileInfo, error) {
+	return []os.FileInfo{}, ErrNotSupported
+}
+
+// ReadDir is not supported for regular files
+func (f *sectionFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.node.Name, Err: ErrNotDirectory}
+}
+
+// Stat returns the FileInfo structure describing file.
+func (f *sectionFile) Stat() (os.FileInfo, error) {
+	return &ResourceFileInfo{Node: f.node}, nil
+}
+
 // ExecutableFunc returns the executable path
 type ExecutableFunc func() (string, error)
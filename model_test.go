@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"sync"
 	"time"
 
@@ -42,7 +43,22 @@ var _ = Describe("Model", func() {
 		})
 
 		It("returns the Mode successfully", func() {
-			Expect(info.Mode()).To(BeZero())
+			Expect(info.Mode()).To(Equal(os.FileMode(0644)))
+		})
+
+		Context("when the node has permissions", func() {
+			It("returns the Mode successfully", func() {
+				node.Mode = 0755
+				Expect(info.Mode()).To(Equal(os.FileMode(0755)))
+			})
+		})
+
+		Context("when the node is directory", func() {
+			It("returns the Mode successfully", func() {
+				node.IsDir = true
+				Expect(info.Mode().IsDir()).To(BeTrue())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+			})
 		})
 
 		It("returns the ModTime successfully", func() {
@@ -54,7 +70,16 @@ var _ = Describe("Model", func() {
 		})
 
 		It("returns the Sys successfully", func() {
-			Expect(info.Sys()).To(BeNil())
+			Expect(info.Sys()).To(Equal(parcello.Metadata{}))
+		})
+
+		Context("when the node has metadata", func() {
+			It("returns the metadata", func() {
+				node.Metadata = parcello.Metadata{parcello.MetadataContentType: "text/plain"}
+
+				Expect(info.Metadata().ContentType()).To(Equal("text/plain"))
+				Expect(parcello.MetadataOf(info)).To(Equal(node.Metadata))
+			})
 		})
 	})
 
@@ -171,6 +196,23 @@ var _ = Describe("Model", func() {
 				})
 			})
 
+			It("reads the directory entries in batches", func() {
+				entries, err := file.ReadDir(1)
+				Expect(err).To(BeNil())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Name()).To(Equal("report.txt"))
+				Expect(entries[0].IsDir()).To(BeFalse())
+
+				entries, err = file.ReadDir(1)
+				Expect(err).To(BeNil())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Name()).To(Equal("sample.txt"))
+
+				entries, err = file.ReadDir(1)
+				Expect(err).To(Equal(io.EOF))
+				Expect(entries).To(BeEmpty())
+			})
+
 			It("returns the information successfully", func() {
 				info, err := file.Stat()
 				Expect(err).To(BeNil())
@@ -0,0 +1,388 @@
+package parcello
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	_ FileSystem     = &MountFileSystem{}
+	_ LinkFileSystem = &MountFileSystem{}
+)
+
+// Mount is a file system mounted at a path of the MountFileSystem
+type Mount struct {
+	// Path is the mount point (the root if it is empty)
+	Path string
+	// FileSystem is the mounted file system
+	FileSystem FileSystem
+}
+
+// ParseMount parses a mount in 'src:dest' format. The destination is the
+// root if it is omitted.
+func ParseMount(value string) (string, string, error) {
+	index := strings.LastIndex(value, ":")
+
+	// the colon of a windows drive letter does not separate the destination
+	if index < 0 || (index == 1 && filepath.VolumeName(value) != "") {
+		return value, "", nil
+	}
+
+	src, dest := value[:index], value[index+1:]
+
+	if src == "" {
+		return "", "", fmt.Errorf("The source of mount '%s' is empty", value)
+	}
+
+	return src, dest, nil
+}
+
+// MountFileSystem is a read-only file system that combines several file
+// systems mounted at different paths (for instance './web/dist' at
+// 'public'). It allows the compressor to bundle resources from several
+// directories.
+type MountFileSystem struct {
+	// Mounts of the file system
+	Mounts []*Mount
+}
+
+// NewMountFileSystem creates a file system of given mounts
+func NewMountFileSystem(mounts ...*Mount) *MountFileSystem {
+	return &MountFileSystem{Mounts: mounts}
+}
+
+// Open opens the named file for reading
+func (m *MountFileSystem) Open(name string) (ReadOnlyFile, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile is the generalized open call; most users will use Open. The file
+// is looked up in the mounts that contain it starting from the deepest one.
+func (m *MountFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if isWritable(flag) || hasFlag(os.O_CREATE, flag) || hasFlag(os.O_TRUNC, flag) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+
+	name = slashPath(name)
+	mounts := m.sorted()
+
+	for index := len(mounts) - 1; index >= 0; index-- {
+		mount := mounts[index]
+
+		rel, ok := mountRel(mountPath(mount), name)
+		if !ok {
+			continue
+		}
+
+		file, err := mount.FileSystem.OpenFile(rel, flag, perm)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		return file, err
+	}
+
+	if children := m.children(name); len(children) > 0 {
+		return m.dir(name, children), nil
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, the returned FileInfo describes the link.
+func (m *MountFileSystem) Lstat(name string) (os.FileInfo, error) {
+	var info os.FileInfo
+
+	err := m.link("lstat", name, func(links LinkFileSystem, rel string) (err error) {
+		info, err = links.Lstat(rel)
+		return err
+	})
+
+	return info, err
+}
+
+// Readlink returns the destination of the named symbolic link
+func (m *MountFileSystem) Readlink(name string) (string, error) {
+	var target string
+
+	err := m.link("readlink", name, func(links LinkFileSystem, rel string) (err error) {
+		target, err = links.Readlink(rel)
+		return err
+	})
+
+	return target, err
+}
+
+// link calls fn for the deepest mount that contains the named file and
+// supports symbolic links
+func (m *MountFileSystem) link(op, name string, fn func(links LinkFileSystem, rel string) error) error {
+	name = slashPath(name)
+	mounts := m.sorted()
+
+	for index := len(mounts) - 1; index >= 0; index-- {
+		mount := mounts[index]
+
+		rel, ok := mountRel(mountPath(mount), name)
+		if !ok {
+			continue
+		}
+
+		links, ok := mount.FileSystem.(LinkFileSystem)
+		if !ok {
+			continue
+		}
+
+		if err := fn(links, rel); !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root. The parent directories of the mount
+// points are walked even if they do not exist in any mount.
+func (m *MountFileSystem) Walk(dir string, fn filepath.WalkFunc) error {
+	root := slashPath(dir)
+
+	walker := &mountWalker{
+		fn:      fn,
+		visited: map[string]bool{},
+		skipped: map[string]bool{},
+	}
+
+	mounts := m.sorted()
+	found := false
+
+	// the deepest mount that contains the root is walked first
+	for index := len(mounts) - 1; index >= 0; index-- {
+		mount := mounts[index]
+
+		rel, ok := mountRel(mountPath(mount), root)
+		if !ok {
+			continue
+		}
+
+		file, err := mount.FileSystem.Open(rel)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		file.Close()
+
+		if err := mount.FileSystem.Walk(rel, walker.walkFunc(mountPath(mount))); err != nil {
+			return err
+		}
+
+		mounts = append(mounts[:index:index], mounts[index+1:]...)
+		found = true
+		break
+	}
+
+	if !found {
+		if len(m.children(root)) == 0 {
+			return &os.PathError{Op: "walk", Path: dir, Err: os.ErrNotExist}
+		}
+
+		if err := walker.virtual(root); err != nil {
+			return walker.skip(err)
+		}
+	}
+
+	for _, mount := range mounts {
+		target := mountPath(mount)
+
+		if target == root || !isParent(root, target) {
+			continue
+		}
+
+		if err := walker.mount(root, target, mount.FileSystem); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sorted returns the mounts sorted by their paths, so the parent mounts
+// precede their children
+func (m *MountFileSystem) sorted() []*Mount {
+	mounts := append([]*Mount{}, m.Mounts...)
+
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return mountPath(mounts[i]) < mountPath(mounts[j])
+	})
+
+	return mounts
+}
+
+// children returns the names of the mount points and their parent
+// directories that reside in the directory
+func (m *MountFileSystem) children(dir string) []string {
+	names := []string{}
+	unique := map[string]bool{}
+
+	for _, mount := range m.Mounts {
+		target := mountPath(mount)
+
+		if target == dir || !isParent(dir, target) {
+			continue
+		}
+
+		rel, _ := mountRel(dir, target)
+		name := strings.Split(rel, "/")[0]
+
+		if !unique[name] {
+			unique[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// dir returns a directory that contains given children
+func (m *MountFileSystem) dir(name string, children []string) File {
+	node := virtualNode(name)
+
+	for _, child := range children {
+		node.Children = append(node.Children, virtualNode(child))
+	}
+
+	return NewResourceFile(node)
+}
+
+// mountWalker walks the mounts and reports every path once
+type mountWalker struct {
+	fn filepath.WalkFunc
+	// visited contains the reported paths
+	visited map[string]bool
+	// skipped contains the directories that have been skipped
+	skipped map[string]bool
+}
+
+// mount walks the mount at target. Its parent directories that have not been
+// reported are reported as virtual directories.
+func (w *mountWalker) mount(root, target string, fileSystem FileSystem) error {
+	parents := []string{}
+
+	for dir := path.Dir(target); dir != root && dir != "."; dir = path.Dir(dir) {
+		parents = append([]string{dir}, parents...)
+	}
+
+	for _, dir := range append([]string{root}, parents...) {
+		if w.skipped[dir] {
+			return nil
+		}
+
+		if w.visited[dir] {
+			continue
+		}
+
+		if err := w.virtual(dir); err != nil {
+			return w.skip(err)
+		}
+	}
+
+	if w.skipped[target] {
+		return nil
+	}
+
+	return fileSystem.Walk("/", w.walkFunc(target))
+}
+
+// virtual reports a directory that does not exist in any mount
+func (w *mountWalker) virtual(dir string) error {
+	w.visited[dir] = true
+
+	err := w.fn(filepath.FromSlash(dir), &ResourceFileInfo{Node: virtualNode(path.Base(dir))}, nil)
+	if err == filepath.SkipDir {
+		w.skipped[dir] = true
+	}
+
+	return err
+}
+
+// walkFunc reports the paths of a mount relative to the root of the mount
+// file system
+func (w *mountWalker) walkFunc(target string) filepath.WalkFunc {
+	return func(name string, info os.FileInfo, err error) error {
+		name = path.Join(target, slashPath(name))
+
+		if err == nil && info != nil {
+			if w.visited[name] {
+				if info.IsDir() {
+					return nil
+				}
+
+				return fmt.Errorf("The resource '%s' is provided by more than one mount", name)
+			}
+
+			w.visited[name] = true
+		}
+
+		err = w.fn(filepath.FromSlash(name), info, err)
+
+		if err == filepath.SkipDir && info != nil && info.IsDir() {
+			w.skipped[name] = true
+		}
+
+		return err
+	}
+}
+
+// skip handles the error returned for a virtual directory
+func (w *mountWalker) skip(err error) error {
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+func virtualNode(name string) *Node {
+	return &Node{
+		Name:    name,
+		IsDir:   true,
+		Content: &[]byte{},
+		Mutex:   &sync.RWMutex{},
+	}
+}
+
+// mountPath returns the clean mount point
+func mountPath(mount *Mount) string {
+	return slashPath(mount.Path)
+}
+
+// mountRel returns the path relative to the mount point if the mount point
+// contains it
+func mountRel(target, name string) (string, bool) {
+	switch {
+	case target == ".":
+		return name, true
+	case target == name:
+		return ".", true
+	case strings.HasPrefix(name, target+"/"):
+		return strings.TrimPrefix(name, target+"/"), true
+	default:
+		return "", false
+	}
+}
+
+// isParent returns true if the directory contains the path
+func isParent(dir, name string) bool {
+	_, ok := mountRel(dir, name)
+	return ok
+}
@@ -0,0 +1,249 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("MountFileSystem", func() {
+	var (
+		fileSystem *parcello.MountFileSystem
+		root       fstest.MapFS
+		templates  fstest.MapFS
+		dist       fstest.MapFS
+	)
+
+	paths := func(dir string) []string {
+		items := []string{}
+
+		err := fileSystem.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			Expect(err).To(BeNil())
+			items = append(items, path)
+			return nil
+		})
+
+		Expect(err).To(BeNil())
+		return items
+	}
+
+	BeforeEach(func() {
+		root = fstest.MapFS{
+			"sql/schema.sql": &fstest.MapFile{Data: []byte("CREATE TABLE users;")},
+		}
+
+		templates = fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte("<html/>")},
+		}
+
+		dist = fstest.MapFS{
+			"app.js":       &fstest.MapFile{Data: []byte("console.log('hello')")},
+			"css/site.css": &fstest.MapFile{Data: []byte("body {}")},
+		}
+
+		fileSystem = parcello.NewMountFileSystem(
+			&parcello.Mount{FileSystem: parcello.FromFS(root)},
+			&parcello.Mount{Path: "public/web", FileSystem: parcello.FromFS(dist)},
+			&parcello.Mount{Path: "templates", FileSystem: parcello.FromFS(templates)},
+		)
+	})
+
+	It("walks the mounts", func() {
+		Expect(paths("/")).To(Equal([]string{
+			".",
+			"sql",
+			"sql/schema.sql",
+			"public",
+			"public/web",
+			"public/web/app.js",
+			"public/web/css",
+			"public/web/css/site.css",
+			"templates",
+			"templates/index.html",
+		}))
+	})
+
+	It("walks a mount point", func() {
+		Expect(paths("public/web/css")).To(Equal([]string{
+			"public/web/css",
+			"public/web/css/site.css",
+		}))
+	})
+
+	It("walks a directory that contains mount points", func() {
+		Expect(paths("public")).To(Equal([]string{
+			"public",
+			"public/web",
+			"public/web/app.js",
+			"public/web/css",
+			"public/web/css/site.css",
+		}))
+	})
+
+	It("opens the files of the mounts", func() {
+		file, err := fileSystem.Open("public/web/app.js")
+		Expect(err).To(BeNil())
+
+		data, err := ioutil.ReadAll(file)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("console.log('hello')"))
+		Expect(file.Close()).To(Succeed())
+
+		file, err = fileSystem.Open("sql/schema.sql")
+		Expect(err).To(BeNil())
+		Expect(file.Close()).To(Succeed())
+	})
+
+	It("opens the parent directories of the mount points", func() {
+		file, err := fileSystem.Open("public")
+		Expect(err).To(BeNil())
+
+		info, err := file.Stat()
+		Expect(err).To(BeNil())
+		Expect(info.IsDir()).To(BeTrue())
+
+		infos, err := file.Readdir(-1)
+		Expect(err).To(BeNil())
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].Name()).To(Equal("web"))
+	})
+
+	It("does not open the missing files", func() {
+		file, err := fileSystem.Open("public/index.html")
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(file).To(BeNil())
+	})
+
+	It("does not open files for writing", func() {
+		file, err := fileSystem.OpenFile("sql/schema.sql", os.O_WRONLY, 0600)
+		Expect(err).To(MatchError("open sql/schema.sql: File is read-only"))
+		Expect(file).To(BeNil())
+	})
+
+	It("compresses the mounts in a single bundle", func() {
+		compressor := &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:       GinkgoWriter,
+				Filename:     "bundle",
+				Recurive:     true,
+				Reproducible: true,
+			},
+		}
+
+		bundle, err := compressor.Compress(&parcello.CompressorContext{FileSystem: fileSystem})
+		Expect(err).To(BeNil())
+
+		reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+		Expect(err).To(BeNil())
+
+		names := []string{}
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+		}
+
+		Expect(names).To(Equal([]string{
+			"public/web/app.js",
+			"public/web/css/site.css",
+			"sql/schema.sql",
+			"templates/index.html",
+		}))
+	})
+
+	Context("when a directory is skipped", func() {
+		It("skips the mounts in it", func() {
+			items := []string{}
+
+			err := fileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+				items = append(items, path)
+
+				if path == "public" {
+					return filepath.SkipDir
+				}
+
+				return nil
+			})
+
+			Expect(err).To(BeNil())
+			Expect(items).To(Equal([]string{
+				".",
+				"sql",
+				"sql/schema.sql",
+				"public",
+				"templates",
+				"templates/index.html",
+			}))
+		})
+	})
+
+	Context("when a directory is provided by several mounts", func() {
+		It("walks it once", func() {
+			root["templates/layout.html"] = &fstest.MapFile{}
+
+			Expect(paths("/")).To(Equal([]string{
+				".",
+				"sql",
+				"sql/schema.sql",
+				"templates",
+				"templates/layout.html",
+				"public",
+				"public/web",
+				"public/web/app.js",
+				"public/web/css",
+				"public/web/css/site.css",
+				"templates/index.html",
+			}))
+		})
+	})
+
+	Context("when a file is provided by several mounts", func() {
+		It("returns an error", func() {
+			root["templates/index.html"] = &fstest.MapFile{}
+
+			err := fileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+				return err
+			})
+
+			Expect(err).To(MatchError("The resource 'templates/index.html' is provided by more than one mount"))
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		It("returns an error", func() {
+			err := fileSystem.Walk("assets", func(path string, info os.FileInfo, err error) error {
+				return err
+			})
+
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("ParseMount", func() {
+	It("parses the source and the destination", func() {
+		src, dest, err := parcello.ParseMount("web/dist:public")
+		Expect(err).To(BeNil())
+		Expect(src).To(Equal("web/dist"))
+		Expect(dest).To(Equal("public"))
+	})
+
+	It("mounts the source at the root by default", func() {
+		src, dest, err := parcello.ParseMount("../shared/templates")
+		Expect(err).To(BeNil())
+		Expect(src).To(Equal("../shared/templates"))
+		Expect(dest).To(BeEmpty())
+	})
+
+	Context("when the source is empty", func() {
+		It("returns an error", func() {
+			_, _, err := parcello.ParseMount(":public")
+			Expect(err).To(MatchError("The source of mount ':public' is empty"))
+		})
+	})
+})
@@ -0,0 +1,204 @@
+package parcello
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConflictError is returned if the resources of a bundle already exist in
+// the resource manager
+type ConflictError struct {
+	// Namespace is the namespace of the bundle (if any)
+	Namespace string
+	// Paths are the paths of the conflicting resources
+	Paths []string
+}
+
+// Error returns the error message
+func (e *ConflictError) Error() string {
+	paths := []string{}
+
+	for _, path := range e.Paths {
+		paths = append(paths, fmt.Sprintf("'%s'", path))
+	}
+
+	message := fmt.Sprintf("Conflicting resources %s", strings.Join(paths, ", "))
+
+	if e.Namespace != "" {
+		message = fmt.Sprintf("%s in namespace '%s'", message, e.Namespace)
+	}
+
+	return message
+}
+
+func (e *ConflictError) contains(path string) bool {
+	if e == nil {
+		return false
+	}
+
+	for _, item := range e.Paths {
+		if item == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+var registry = &namespaces{managers: map[string]*ResourceManager{}}
+
+// namespaces keeps the resource managers of the registered namespaces
+type namespaces struct {
+	mu       sync.Mutex
+	managers map[string]*ResourceManager
+}
+
+// manager returns the resource manager of the namespace. The manager is
+// linked to the parent manager: it shares its public key and its health, so
+// its failures are reported by the parent.
+func (n *namespaces) manager(name string, parent *ResourceManager) *ResourceManager {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	manager, ok := n.managers[name]
+	if !ok {
+		manager = &ResourceManager{
+			root:      &Node{Name: "/", IsDir: true, Mutex: &sync.RWMutex{}},
+			checks:    parent.health(),
+			PublicKey: parent.publicKey(),
+		}
+
+		n.managers[name] = manager
+	}
+
+	return manager
+}
+
+// lookup returns the resource manager of the registered namespace
+func (n *namespaces) lookup(name string) (*ResourceManager, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	manager, ok := n.managers[name]
+	return manager, ok
+}
+
+// linked returns the managers of the namespaces that share the health of
+// given manager (except the manager itself)
+func (n *namespaces) linked(parent *ResourceManager) []*ResourceManager {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	health := parent.health()
+	managers := []*ResourceManager{}
+
+	for _, manager := range n.managers {
+		if manager != parent && manager.health() == health {
+			managers = append(managers, manager)
+		}
+	}
+
+	return managers
+}
+
+// Namespace returns the resource manager of the resources registered under
+// given namespace (by default the import path of the package that embeds
+// them) and reports whether the namespace is registered. In dev mode it
+// returns the default manager. If the default manager is an overlay, the
+// namespace is stacked under its upper layers.
+func Namespace(name string) (FileSystemManager, bool) {
+	manager, ok := resourceManager(Manager)
+	if !ok {
+		return Manager, true
+	}
+
+	namespace, ok := registry.lookup(name)
+	if !ok {
+		return nil, false
+	}
+
+	if overlay, ok := Manager.(*Overlay); ok {
+		layers := []FileSystemManager{}
+
+		for _, layer := range overlay.Layers {
+			if layer == FileSystemManager(manager) {
+				layer = namespace
+			}
+
+			layers = append(layers, layer)
+		}
+
+		return NewOverlay(layers...), true
+	}
+
+	return namespace, true
+}
+
+// Namespaces returns the names of the registered namespaces
+func Namespaces() []string {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	names := []string{}
+
+	for name := range registry.managers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// AddNamespaceResource adds resource to given namespace. The resource is
+// merged in the default manager as well. The conflicting resources (with the
+// default manager or with the other resources of the namespace) are not
+// merged, but they are reported by Err.
+func AddNamespaceResource(namespace string, resource []byte) {
+	addNamespace(namespace, BinaryResource(resource))
+}
+
+// AddNamespaceFS adds the content of fs.FS (for instance embed.FS) to given
+// namespace. The resources are merged in the default manager as well.
+func AddNamespaceFS(namespace string, fileSystem fs.FS) {
+	addNamespace(namespace, &Resource{FS: fileSystem})
+}
+
+func addNamespace(namespace string, resource *Resource) {
+	manager, ok := resourceManager(Manager)
+	if !ok {
+		// dev mode, the resources are served from the file system
+		if err := Manager.Add(resource); err != nil {
+			panic(err)
+		}
+
+		return
+	}
+
+	// the failures are reported by the default manager, which shares its
+	// health with the namespace
+	if err := registry.manager(namespace, manager).merge(resource); err != nil {
+		conflict, ok := err.(*ConflictError)
+		if !ok {
+			// the bundle is invalid or it is refused by the verification, so
+			// it is not merged
+			manager.health().fail(err)
+			return
+		}
+
+		conflict.Namespace = namespace
+		manager.health().conflict(conflict)
+	}
+
+	if err := manager.merge(resource); err != nil {
+		if conflict, ok := err.(*ConflictError); ok {
+			conflict.Namespace = namespace
+			manager.health().conflict(conflict)
+			return
+		}
+
+		manager.health().fail(err)
+	}
+}
@@ -0,0 +1,296 @@
+package parcello_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/parcello/fake"
+)
+
+var _ = Describe("Namespace", func() {
+	var (
+		original parcello.FileSystemManager
+		manager  *parcello.ResourceManager
+		alpha    string
+		beta     string
+		count    int
+	)
+
+	BeforeEach(func() {
+		// the namespaces are registered globally
+		count++
+		alpha = fmt.Sprintf("github.com/phogolabs/alpha%d", count)
+		beta = fmt.Sprintf("github.com/phogolabs/beta%d", count)
+
+		manager = &parcello.ResourceManager{}
+
+		original = parcello.Manager
+		parcello.Manager = manager
+	})
+
+	AfterEach(func() {
+		parcello.Manager = original
+	})
+
+	namespace := func(name string) parcello.FileSystemManager {
+		manager, ok := parcello.Namespace(name)
+		Expect(ok).To(BeTrue())
+		return manager
+	}
+
+	read := func(fileSystem parcello.FileSystem, name string) string {
+		file, err := fileSystem.Open(name)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		data, err := ioutil.ReadAll(file)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	Context("when two namespaces contain the same resource", func() {
+		BeforeEach(func() {
+			parcello.AddNamespaceFS(alpha, fstest.MapFS{
+				"migrations/001.sql": &fstest.MapFile{Data: []byte("CREATE TABLE alpha;")},
+			})
+
+			parcello.AddNamespaceFS(beta, fstest.MapFS{
+				"migrations/001.sql": &fstest.MapFile{Data: []byte("CREATE TABLE beta;")},
+				"migrations/002.sql": &fstest.MapFile{Data: []byte("CREATE TABLE gamma;")},
+			})
+		})
+
+		It("keeps the resources of every namespace", func() {
+			Expect(read(namespace(alpha), "/migrations/001.sql")).To(Equal("CREATE TABLE alpha;"))
+			Expect(read(namespace(beta), "/migrations/001.sql")).To(Equal("CREATE TABLE beta;"))
+			Expect(parcello.Namespaces()).To(ContainElements(alpha, beta))
+		})
+
+		It("merges the resources that do not conflict in the default manager", func() {
+			Expect(read(manager, "/migrations/001.sql")).To(Equal("CREATE TABLE alpha;"))
+			Expect(read(manager, "/migrations/002.sql")).To(Equal("CREATE TABLE gamma;"))
+		})
+
+		It("reports the conflicts", func() {
+			err := parcello.Err()
+			Expect(err).To(MatchError(fmt.Sprintf("Invalid bundle: Conflicting resources 'migrations/001.sql' in namespace '%s'", beta)))
+
+			bundleErr := &parcello.BundleError{}
+			Expect(errors.As(err, &bundleErr)).To(BeTrue())
+			Expect(bundleErr.Conflicts).To(HaveLen(1))
+			Expect(bundleErr.Conflicts[0].Namespace).To(Equal(beta))
+			Expect(bundleErr.Conflicts[0].Paths).To(ConsistOf("migrations/001.sql"))
+		})
+	})
+
+	Context("when the resource is binary", func() {
+		It("adds the resource to the namespace", func() {
+			compressor := &parcello.ZipCompressor{
+				Config: &parcello.CompressorConfig{
+					Logger:   GinkgoWriter,
+					Filename: "bundle",
+					Recurive: true,
+				},
+			}
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.FromFS(fstest.MapFS{
+					"templates/index.html": &fstest.MapFile{Data: []byte("<html></html>")},
+				}),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			parcello.AddNamespaceResource("github.com/phogolabs/delta", bundle.Body)
+
+			Expect(read(namespace("github.com/phogolabs/delta"), "/templates/index.html")).To(Equal("<html></html>"))
+			Expect(read(manager, "/templates/index.html")).To(Equal("<html></html>"))
+			Expect(parcello.Err()).To(Succeed())
+		})
+	})
+
+	Context("when the bundles of several namespaces have a manifest", func() {
+		It("merges the manifests without conflicts", func() {
+			compress := func(name, content string) []byte {
+				compressor := &parcello.ZipCompressor{
+					Config: &parcello.CompressorConfig{
+						Logger:   GinkgoWriter,
+						Filename: "bundle",
+						Recurive: true,
+						Manifest: true,
+					},
+				}
+
+				bundle, err := compressor.Compress(&parcello.CompressorContext{
+					FileSystem: parcello.FromFS(fstest.MapFS{
+						name: &fstest.MapFile{Data: []byte(content)},
+					}),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return bundle.Body
+			}
+
+			parcello.AddNamespaceResource(alpha, compress("js/alpha.js", "alpha();"))
+			parcello.AddNamespaceResource(beta, compress("js/beta.js", "beta();"))
+
+			Expect(parcello.Err()).To(Succeed())
+
+			for _, name := range []string{"js/alpha.js", "js/beta.js"} {
+				path, err := manager.AssetPath(name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path).To(MatchRegexp(`^js/(alpha|beta)\.[0-9a-f]{8}\.js$`))
+			}
+
+			path, err := namespace(beta).AssetPath("js/beta.js")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(MatchRegexp(`^js/beta\.[0-9a-f]{8}\.js$`))
+		})
+	})
+
+	Describe("Health", func() {
+		var (
+			publicKey ed25519.PublicKey
+			bundle    func(content string) []byte
+		)
+
+		BeforeEach(func() {
+			key, privateKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			publicKey = key
+
+			bundle = func(content string) []byte {
+				compressor := &parcello.ZipCompressor{
+					Config: &parcello.CompressorConfig{
+						Logger:     GinkgoWriter,
+						Filename:   "bundle",
+						Recurive:   true,
+						PrivateKey: privateKey,
+					},
+				}
+
+				bundle, err := compressor.Compress(&parcello.CompressorContext{
+					FileSystem: parcello.FromFS(fstest.MapFS{
+						"index.html": &fstest.MapFile{Data: []byte(content)},
+					}),
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return bundle.Body
+			}
+		})
+
+		It("reports the invalid entries of the namespaces", func() {
+			parcello.AddNamespaceResource(alpha, bundle("alpha"))
+			parcello.AddNamespaceResource(beta, tamper(bundle("beta"), "index.html", "tampered"))
+
+			// the resource of beta is not merged in the default manager
+			Expect(read(manager, "/index.html")).To(Equal("alpha"))
+			Expect(parcello.Err()).To(MatchError(HavePrefix("Invalid bundle: Conflicting resources 'index.html'")))
+
+			_, err := namespace(beta).Open("/index.html")
+			Expect(err).To(MatchError("open index.html: Hash mismatch"))
+			Expect(parcello.Err()).To(MatchError(HaveSuffix("; index.html: Hash mismatch")))
+		})
+
+		It("validates the resources of the namespaces", func() {
+			parcello.AddNamespaceResource(alpha, bundle("alpha"))
+			parcello.AddNamespaceResource(beta, tamper(bundle("beta"), "index.html", "tampered"))
+
+			err := parcello.Validate()
+			Expect(err).To(MatchError(HaveSuffix("; index.html: Hash mismatch")))
+
+			bundleErr := &parcello.BundleError{}
+			Expect(errors.As(err, &bundleErr)).To(BeTrue())
+			Expect(bundleErr.Entries).To(HaveLen(1))
+			Expect(parcello.Err()).To(Equal(err))
+		})
+
+		It("verifies the bundles of the namespaces", func() {
+			parcello.AddNamespaceResource(alpha, bundle("alpha"))
+			Expect(parcello.Verify(publicKey)).To(Succeed())
+			Expect(parcello.Err()).To(Succeed())
+
+			parcello.AddNamespaceResource(beta, tamper(bundle("beta"), "index.html", "tampered"))
+
+			_, err := namespace(beta).Open("/index.html")
+			Expect(err).To(MatchError("open /index.html: file does not exist"))
+			Expect(errors.Is(parcello.Err(), parcello.ErrInvalidSignature)).To(BeTrue())
+		})
+
+		Context("when the bundle of a namespace is tampered", func() {
+			It("reports the verification failure", func() {
+				parcello.AddNamespaceResource(alpha, tamper(bundle("alpha"), "index.html", "tampered"))
+
+				Expect(parcello.Verify(publicKey)).To(Equal(parcello.ErrInvalidSignature))
+				Expect(errors.Is(parcello.Err(), parcello.ErrInvalidSignature)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("when the namespace is not registered", func() {
+		It("returns false", func() {
+			manager, ok := parcello.Namespace("github.com/phogolabs/unknown")
+			Expect(ok).To(BeFalse())
+			Expect(manager).To(BeNil())
+			Expect(parcello.Namespaces()).NotTo(ContainElement("github.com/phogolabs/unknown"))
+		})
+	})
+
+	Context("when the resources of a namespace conflict", func() {
+		BeforeEach(func() {
+			parcello.AddNamespaceFS(alpha, fstest.MapFS{
+				"migrations/001.sql": &fstest.MapFile{Data: []byte("CREATE TABLE alpha;")},
+			})
+
+			parcello.AddNamespaceFS(alpha, fstest.MapFS{
+				"migrations/001.sql": &fstest.MapFile{Data: []byte("CREATE TABLE beta;")},
+				"migrations/002.sql": &fstest.MapFile{Data: []byte("CREATE TABLE gamma;")},
+			})
+		})
+
+		It("merges the resources that do not conflict", func() {
+			Expect(read(namespace(alpha), "/migrations/001.sql")).To(Equal("CREATE TABLE alpha;"))
+			Expect(read(namespace(alpha), "/migrations/002.sql")).To(Equal("CREATE TABLE gamma;"))
+			Expect(read(manager, "/migrations/002.sql")).To(Equal("CREATE TABLE gamma;"))
+		})
+
+		It("reports the conflicts through the default manager", func() {
+			err := parcello.Err()
+			Expect(err).To(MatchError(fmt.Sprintf("Invalid bundle: Conflicting resources 'migrations/001.sql' in namespace '%s'", alpha)))
+
+			bundleErr := &parcello.BundleError{}
+			Expect(errors.As(err, &bundleErr)).To(BeTrue())
+			Expect(bundleErr.Conflicts).To(HaveLen(1))
+		})
+	})
+
+	Context("when the bundle of a namespace is invalid", func() {
+		It("reports the error through the default manager", func() {
+			parcello.AddNamespaceResource(alpha, []byte("invalid"))
+
+			bundleErr := &parcello.BundleError{}
+			Expect(errors.As(parcello.Err(), &bundleErr)).To(BeTrue())
+			Expect(bundleErr.Err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the default manager is not a resource manager", func() {
+		It("returns the default manager", func() {
+			fileSystem := &fake.FileSystemManager{}
+			parcello.Manager = fileSystem
+
+			manager, ok := parcello.Namespace(alpha)
+			Expect(ok).To(BeTrue())
+			Expect(manager).To(Equal(fileSystem))
+
+			parcello.AddNamespaceFS("github.com/phogolabs/epsilon", fstest.MapFS{})
+			Expect(fileSystem.AddCallCount()).To(Equal(1))
+		})
+	})
+})
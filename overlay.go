@@ -0,0 +1,385 @@
+package parcello
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var _ FileSystemManager = &Overlay{}
+
+// WhiteoutPrefix is the prefix of the whiteout files. A whiteout file named
+// '.wh.index.html' hides 'index.html' in the lower layers of the Overlay.
+const WhiteoutPrefix = ".wh."
+
+// Overlay is a file system manager that stacks file systems (for instance a
+// local directory over the embedded resources). The layers are ordered by
+// precedence, the first layer is the top one. The files are looked up from
+// the top layer down, the directory listings are merged and the writes go to
+// the top layer.
+type Overlay struct {
+	// Layers of the overlay ordered by precedence (highest first)
+	Layers []FileSystemManager
+}
+
+// NewOverlay creates an overlay of given layers ordered by precedence
+// (highest first)
+func NewOverlay(layers ...FileSystemManager) *Overlay {
+	return &Overlay{Layers: layers}
+}
+
+// Open opens the named file for reading
+func (o *Overlay) Open(name string) (ReadOnlyFile, error) {
+	return o.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile is the generalized open call; most users will use Open. The file
+// is opened for writing in the top layer. If it exists only in a lower
+// layer, its content is copied to the top layer first.
+func (o *Overlay) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if isWritable(flag) || hasFlag(os.O_CREATE, flag) || hasFlag(os.O_TRUNC, flag) {
+		return o.write(name, flag, perm)
+	}
+
+	index, file, err := o.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return file, nil
+	}
+
+	infos, err := o.readdir(name, index)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &overlayDir{File: file, infos: infos}, nil
+}
+
+// Walk walks the file tree rooted at root, calling walkFn for each file or
+// directory in the tree, including root.
+func (o *Overlay) Walk(dir string, fn filepath.WalkFunc) error {
+	file, err := o.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	err = o.walk(dir, info, fn)
+
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+// Dir returns an overlay of the sub-managers of given path
+func (o *Overlay) Dir(name string) (FileSystemManager, error) {
+	layers := []FileSystemManager{}
+
+	for _, layer := range o.Layers {
+		if sub, err := layer.Dir(name); err == nil {
+			layers = append(layers, sub)
+		}
+
+		if whiteout(layer, name) {
+			break
+		}
+	}
+
+	if len(layers) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	return &Overlay{Layers: layers}, nil
+}
+
+// Add adds resource bundle to the bottom layer
+func (o *Overlay) Add(resource *Resource) error {
+	if len(o.Layers) == 0 {
+		return nil
+	}
+
+	return o.Layers[len(o.Layers)-1].Add(resource)
+}
+
+// AssetPath returns the fingerprinted path of the named resource. It is
+// computed from the content of the layer that provides the resource.
+func (o *Overlay) AssetPath(name string) (string, error) {
+	return Fingerprint(o, name)
+}
+
+// Whiteout hides the named file of the lower layers by creating a whiteout
+// file in the top layer
+func (o *Overlay) Whiteout(name string) error {
+	if len(o.Layers) == 0 {
+		return &os.PathError{Op: "whiteout", Path: name, Err: os.ErrNotExist}
+	}
+
+	file, err := o.Layers[0].OpenFile(whiteoutPath(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (o *Overlay) lookup(name string) (int, File, error) {
+	if !strings.HasPrefix(path.Base(filepath.ToSlash(name)), WhiteoutPrefix) {
+		for index, layer := range o.Layers {
+			file, err := layer.OpenFile(name, os.O_RDONLY, 0)
+
+			if err == nil {
+				return index, file, nil
+			}
+
+			if !os.IsNotExist(err) {
+				return -1, nil, err
+			}
+
+			if whiteout(layer, name) {
+				break
+			}
+		}
+	}
+
+	return -1, nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+func (o *Overlay) write(name string, flag int, perm os.FileMode) (File, error) {
+	if len(o.Layers) == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	top := o.Layers[0]
+
+	index, file, err := o.lookup(name)
+
+	switch {
+	case os.IsNotExist(err):
+		return top.OpenFile(name, flag, perm)
+	case err != nil:
+		return nil, err
+	}
+
+	defer file.Close()
+
+	if hasFlag(os.O_CREATE|os.O_EXCL, flag) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+
+	if index == 0 {
+		return top.OpenFile(name, flag, perm)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+	}
+
+	// copy up the file from the lower layer
+	target, err := top.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasFlag(os.O_TRUNC, flag) {
+		if _, err = io.Copy(target, file); err != nil {
+			target.Close()
+			return nil, err
+		}
+	}
+
+	if err = target.Close(); err != nil {
+		return nil, err
+	}
+
+	return top.OpenFile(name, flag&^os.O_EXCL, perm)
+}
+
+func (o *Overlay) readdir(name string, index int) ([]os.FileInfo, error) {
+	var (
+		infos  = []os.FileInfo{}
+		seen   = map[string]bool{}
+		hidden = map[string]bool{}
+	)
+
+	for _, layer := range o.Layers[index:] {
+		entries, err := readdir(layer, name)
+
+		switch {
+		case err == ErrNotDirectory:
+			// the file of the lower layer is hidden by the directory
+			return sortInfos(infos), nil
+		case err != nil && !os.IsNotExist(err):
+			return nil, err
+		}
+
+		whiteouts := []string{}
+
+		for _, entry := range entries {
+			key := entry.Name()
+
+			if strings.HasPrefix(key, WhiteoutPrefix) {
+				whiteouts = append(whiteouts, strings.TrimPrefix(key, WhiteoutPrefix))
+				continue
+			}
+
+			if seen[key] || hidden[key] {
+				continue
+			}
+
+			seen[key] = true
+			infos = append(infos, entry)
+		}
+
+		// the whiteouts hide the files of the lower layers only
+		for _, key := range whiteouts {
+			hidden[key] = true
+		}
+
+		if whiteout(layer, name) {
+			break
+		}
+	}
+
+	return sortInfos(infos), nil
+}
+
+func (o *Overlay) walk(name string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if err := fn(name, info, nil); err != nil {
+		if err == filepath.SkipDir && info.IsDir() {
+			return nil
+		}
+
+		return err
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	file, err := o.Open(name)
+	if err != nil {
+		return fn(name, info, err)
+	}
+
+	infos, err := file.Readdir(-1)
+	file.Close()
+
+	if err != nil {
+		return fn(name, info, err)
+	}
+
+	for _, child := range infos {
+		if err := o.walk(filepath.Join(name, child.Name()), child, fn); err != nil {
+			if err == filepath.SkipDir && !child.IsDir() {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overlayDir is a directory which entries are merged from all layers
+type overlayDir struct {
+	File
+	infos []os.FileInfo
+}
+
+// Readdir reads the merged contents of the directory
+func (d *overlayDir) Readdir(count int) ([]os.FileInfo, error) {
+	if count <= 0 {
+		infos := d.infos
+		d.infos = []os.FileInfo{}
+		return infos, nil
+	}
+
+	if len(d.infos) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > len(d.infos) {
+		count = len(d.infos)
+	}
+
+	infos := d.infos[:count]
+	d.infos = d.infos[count:]
+	return infos, nil
+}
+
+// readdir reads the named directory of the layer
+func readdir(layer FileSystem, name string) ([]os.FileInfo, error) {
+	file, err := layer.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, ErrNotDirectory
+	}
+
+	return file.Readdir(-1)
+}
+
+// whiteout returns true if the layer hides the named file or any of its
+// parent directories
+func whiteout(layer FileSystem, name string) bool {
+	name = path.Clean("/" + filepath.ToSlash(name))
+
+	for ; name != "/"; name = path.Dir(name) {
+		file, err := layer.Open(whiteoutPath(name))
+		if err == nil {
+			file.Close()
+			return true
+		}
+	}
+
+	return false
+}
+
+func whiteoutPath(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	return path.Join(path.Dir(name), WhiteoutPrefix+path.Base(name))
+}
+
+func sortInfos(infos []os.FileInfo) []os.FileInfo {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	return infos
+}
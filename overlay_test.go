@@ -0,0 +1,287 @@
+package parcello_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Overlay", func() {
+	var (
+		overlay *parcello.Overlay
+		bundle  *parcello.ResourceManager
+		dir     string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "parcello")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "templates"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "templates", "index.html"), []byte("patched"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "templates", "footer.html"), []byte("footer"), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "templates", ".wh.debug.html"), []byte{}, 0600)).To(Succeed())
+
+		bundle = &parcello.ResourceManager{}
+
+		Expect(bundle.Add(parcello.FSResource(fstest.MapFS{
+			"templates/index.html":  &fstest.MapFile{Data: []byte("embedded")},
+			"templates/header.html": &fstest.MapFile{Data: []byte("header")},
+			"templates/debug.html":  &fstest.MapFile{Data: []byte("debug")},
+			"scripts/app.js":        &fstest.MapFile{Data: []byte("app")},
+		}))).To(Succeed())
+
+		overlay = parcello.NewOverlay(parcello.Dir(dir), bundle)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	read := func(name string) string {
+		file, err := overlay.Open(name)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		data, err := ioutil.ReadAll(file)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	names := func(name string) []string {
+		file, err := overlay.Open(name)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		infos, err := file.Readdir(-1)
+		Expect(err).NotTo(HaveOccurred())
+
+		items := []string{}
+		for _, info := range infos {
+			items = append(items, info.Name())
+		}
+
+		return items
+	}
+
+	Describe("Open", func() {
+		It("opens the file of the top layer", func() {
+			Expect(read("/templates/index.html")).To(Equal("patched"))
+		})
+
+		It("opens the file of the lower layer", func() {
+			Expect(read("/templates/header.html")).To(Equal("header"))
+			Expect(read("/scripts/app.js")).To(Equal("app"))
+		})
+
+		Context("when the file is hidden by a whiteout", func() {
+			It("returns an error", func() {
+				file, err := overlay.Open("/templates/debug.html")
+				Expect(file).To(BeNil())
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the whiteout file is opened", func() {
+			It("returns an error", func() {
+				_, err := overlay.Open("/templates/.wh.debug.html")
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the parent directory is hidden by a whiteout", func() {
+			BeforeEach(func() {
+				Expect(overlay.Whiteout("/scripts")).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := overlay.Open("/scripts/app.js")
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				Expect(names("/")).To(Equal([]string{"templates"}))
+			})
+		})
+	})
+
+	Describe("Readdir", func() {
+		It("merges the entries of all layers", func() {
+			Expect(names("/templates")).To(Equal([]string{"footer.html", "header.html", "index.html"}))
+			Expect(names("/")).To(Equal([]string{"scripts", "templates"}))
+		})
+
+		It("reads the entries in chunks", func() {
+			file, err := overlay.Open("/templates")
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			infos, err := file.Readdir(2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(infos).To(HaveLen(2))
+
+			infos, err = file.Readdir(2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(infos).To(HaveLen(1))
+
+			_, err = file.Readdir(2)
+			Expect(err).To(Equal(io.EOF))
+		})
+	})
+
+	Describe("Walk", func() {
+		It("walks the merged tree", func() {
+			paths := []string{}
+
+			err := overlay.Walk("/", func(path string, info os.FileInfo, err error) error {
+				Expect(err).NotTo(HaveOccurred())
+				paths = append(paths, path)
+				return nil
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(Equal([]string{
+				"/",
+				"/scripts",
+				"/scripts/app.js",
+				"/templates",
+				"/templates/footer.html",
+				"/templates/header.html",
+				"/templates/index.html",
+			}))
+		})
+
+		Context("when a directory is skipped", func() {
+			It("does not walk it", func() {
+				paths := []string{}
+
+				err := overlay.Walk("/", func(path string, info os.FileInfo, err error) error {
+					if path == "/scripts" {
+						return filepath.SkipDir
+					}
+
+					paths = append(paths, path)
+					return nil
+				})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).NotTo(ContainElement("/scripts/app.js"))
+				Expect(paths).To(ContainElement("/templates/index.html"))
+			})
+		})
+	})
+
+	Describe("OpenFile", func() {
+		It("writes the file to the top layer", func() {
+			file, err := overlay.OpenFile("/scripts/vendor.js", os.O_WRONLY|os.O_CREATE, 0600)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = file.Write([]byte("vendor"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "scripts", "vendor.js"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("vendor"))
+
+			Expect(names("/scripts")).To(Equal([]string{"app.js", "vendor.js"}))
+		})
+
+		Context("when the file exists in the lower layer", func() {
+			It("copies up the file", func() {
+				file, err := overlay.OpenFile("/scripts/app.js", os.O_WRONLY|os.O_APPEND, 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = file.Write([]byte(".min"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file.Close()).To(Succeed())
+
+				Expect(read("/scripts/app.js")).To(Equal("app.min"))
+
+				data, err := bundle.ReadFile("/scripts/app.js")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).To(Equal("app"))
+			})
+
+			Context("when the file is truncated", func() {
+				It("does not copy the content", func() {
+					file, err := overlay.OpenFile("/scripts/app.js", os.O_WRONLY|os.O_TRUNC, 0600)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(file.Close()).To(Succeed())
+
+					Expect(read("/scripts/app.js")).To(BeEmpty())
+				})
+			})
+
+			Context("when the file is created exclusively", func() {
+				It("returns an error", func() {
+					_, err := overlay.OpenFile("/scripts/app.js", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+					Expect(os.IsExist(err)).To(BeTrue())
+				})
+			})
+		})
+
+		Context("when the file is hidden by a whiteout", func() {
+			It("creates the file", func() {
+				file, err := overlay.OpenFile("/templates/debug.html", os.O_WRONLY|os.O_CREATE, 0600)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = file.Write([]byte("restored"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file.Close()).To(Succeed())
+
+				Expect(read("/templates/debug.html")).To(Equal("restored"))
+			})
+		})
+	})
+
+	Describe("Dir", func() {
+		It("returns an overlay of the sub-directories", func() {
+			manager, err := overlay.Dir("/templates")
+			Expect(err).NotTo(HaveOccurred())
+
+			overlay = manager.(*parcello.Overlay)
+			Expect(overlay.Layers).To(HaveLen(2))
+			Expect(read("/index.html")).To(Equal("patched"))
+			Expect(names("/")).To(Equal([]string{"footer.html", "header.html", "index.html"}))
+		})
+
+		Context("when the directory does not exist", func() {
+			It("returns an error", func() {
+				overlay = parcello.NewOverlay(bundle)
+
+				manager, err := overlay.Dir("/unknown")
+				Expect(manager).To(BeNil())
+				Expect(err).To(Equal(os.ErrNotExist))
+			})
+		})
+	})
+
+	Describe("Add", func() {
+		It("adds the resource to the bottom layer", func() {
+			Expect(overlay.Add(parcello.FSResource(fstest.MapFS{
+				"styles/app.css": &fstest.MapFile{Data: []byte("body {}")},
+			}))).To(Succeed())
+
+			data, err := bundle.ReadFile("/styles/app.css")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("body {}"))
+		})
+	})
+
+	Describe("AssetPath", func() {
+		It("fingerprints the content of the top layer", func() {
+			path, err := overlay.AssetPath("/templates/index.html")
+			Expect(err).NotTo(HaveOccurred())
+
+			expected, err := parcello.Fingerprint(parcello.Dir(dir), "/templates/index.html")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(expected))
+		})
+	})
+})
@@ -0,0 +1,164 @@
+package parcello
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+const (
+	// metadataSignature is the key of the Ed25519 signature of the bundle
+	metadataSignature = "ed25519"
+)
+
+var (
+	// ErrUnsigned is returned if the bundle is verified, but it does not have a signature.
+	ErrUnsigned = errors.New("Bundle is not signed")
+	// ErrInvalidSignature is returned if the signature does not match the content of the bundle.
+	ErrInvalidSignature = errors.New("Invalid bundle signature")
+)
+
+// ParsePrivateKey parses a PEM encoded PKCS #8 Ed25519 private key (for
+// instance generated by openssl genpkey -algorithm ed25519)
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Invalid PEM encoded private key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported private key type %T", key)
+	}
+
+	return privateKey, nil
+}
+
+// ParsePublicKey parses a PEM encoded PKIX Ed25519 public key (for instance
+// generated by openssl pkey -pubout)
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Invalid PEM encoded public key")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported public key type %T", key)
+	}
+
+	return publicKey, nil
+}
+
+// VerifyResource verifies that the bundle of the resource (for instance a
+// binary with bundled resources) is signed by the private key of given
+// public key. The bundle is read the same way as by NewResourceManager. The
+// file system resources cannot be signed, so ErrUnsigned is returned for
+// them.
+func VerifyResource(resource *Resource, key ed25519.PublicKey) error {
+	if resource.FS != nil {
+		return ErrUnsigned
+	}
+
+	manager := &ResourceManager{
+		NewReader: zip.NewReader,
+		PublicKey: key,
+	}
+
+	return manager.Add(resource)
+}
+
+// sign returns the archive comment that contains the signature of given
+// entries (see signedEntry)
+func sign(entries map[string]string, key ed25519.PrivateKey) string {
+	signature := ed25519.Sign(key, digest(entries))
+
+	return encodeMetadata(url.Values{
+		metadataSignature: {base64.StdEncoding.EncodeToString(signature)},
+	})
+}
+
+// verify verifies the signature of the bundle against the checksums of the
+// content, the modes and the comments of its entries
+func verify(reader *zip.Reader, key ed25519.PublicKey) error {
+	value := decodeMetadata(reader.Comment).Get(metadataSignature)
+	if value == "" {
+		return ErrUnsigned
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	entries := map[string]string{}
+
+	for _, header := range reader.File {
+		file, err := header.Open()
+		if err != nil {
+			return err
+		}
+
+		checksum, err := hash(file)
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+
+		entries[header.Name] = signedEntry(checksum, &header.FileHeader)
+	}
+
+	if !ed25519.Verify(key, digest(entries), signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// signedEntry returns the signed attributes of the entry: the checksum of its
+// content, its mode and its comment, which contains the content type and the
+// custom metadata. The comment is signed in the canonical form of the values
+// that are decoded by the manager.
+func signedEntry(checksum string, header *zip.FileHeader) string {
+	comment := encodeMetadata(decodeMetadata(header.Comment))
+	return fmt.Sprintf("%s  %s  %s", checksum, header.Mode(), comment)
+}
+
+// digest returns the signed message of the bundle. It lists the signed
+// attributes of the entries followed by their names sorted by name in the
+// format of sha256sum.
+func digest(entries map[string]string) []byte {
+	names := []string{}
+
+	for name := range entries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	buffer := &bytes.Buffer{}
+
+	for _, name := range names {
+		fmt.Fprintf(buffer, "%s  %s\n", entries[name], name)
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,310 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Signature", func() {
+	var (
+		publicKey  ed25519.PublicKey
+		privateKey ed25519.PrivateKey
+		compressor *parcello.ZipCompressor
+		bundle     *parcello.Bundle
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		compressor = &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:     GinkgoWriter,
+				Filename:   "bundle",
+				Recurive:   true,
+				PrivateKey: privateKey,
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+
+		bundle, err = compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.FromFS(fstest.MapFS{
+				"index.html":  &fstest.MapFile{Data: []byte("<html></html>")},
+				"css/app.css": &fstest.MapFile{Data: []byte("body {}")},
+			}),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("verifies the signed bundle", func() {
+		Expect(parcello.VerifyResource(parcello.BinaryResource(bundle.Body), publicKey)).To(Succeed())
+	})
+
+	It("verifies the bundle appended to a binary", func() {
+		data := append([]byte("binary"), bundle.Body...)
+		Expect(parcello.VerifyResource(parcello.BinaryResource(data), publicKey)).To(Succeed())
+	})
+
+	Context("when the resource is a file system", func() {
+		It("returns an error", func() {
+			resource := parcello.FSResource(fstest.MapFS{})
+			Expect(parcello.VerifyResource(resource, publicKey)).To(Equal(parcello.ErrUnsigned))
+		})
+	})
+
+	Context("when the public key does not match", func() {
+		It("returns an error", func() {
+			key, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = parcello.VerifyResource(parcello.BinaryResource(bundle.Body), key)
+			Expect(err).To(Equal(parcello.ErrInvalidSignature))
+		})
+	})
+
+	Context("when the bundle is tampered", func() {
+		It("returns an error", func() {
+			body := tamper(bundle.Body, "index.html", "<html>tampered</html>")
+
+			err := parcello.VerifyResource(parcello.BinaryResource(body), publicKey)
+			Expect(err).To(Equal(parcello.ErrInvalidSignature))
+		})
+	})
+
+	Context("when the bundle is copied", func() {
+		It("verifies the copy", func() {
+			body := rewrite(bundle.Body, func(header *zip.FileHeader, data []byte) []byte {
+				return data
+			})
+
+			Expect(parcello.VerifyResource(parcello.BinaryResource(body), publicKey)).To(Succeed())
+		})
+	})
+
+	Context("when the metadata of an entry is tampered", func() {
+		It("returns an error", func() {
+			body := rewrite(bundle.Body, func(header *zip.FileHeader, data []byte) []byte {
+				if header.Name == "css/app.css" {
+					values, err := url.ParseQuery(header.Comment)
+					Expect(err).NotTo(HaveOccurred())
+
+					values.Set(parcello.MetadataContentType, "text/html; charset=utf-8")
+					header.Comment = values.Encode()
+				}
+
+				return data
+			})
+
+			err := parcello.VerifyResource(parcello.BinaryResource(body), publicKey)
+			Expect(err).To(Equal(parcello.ErrInvalidSignature))
+		})
+	})
+
+	Context("when the mode of an entry is tampered", func() {
+		It("returns an error", func() {
+			body := rewrite(bundle.Body, func(header *zip.FileHeader, data []byte) []byte {
+				if header.Name == "index.html" {
+					header.SetMode(0755)
+				}
+
+				return data
+			})
+
+			err := parcello.VerifyResource(parcello.BinaryResource(body), publicKey)
+			Expect(err).To(Equal(parcello.ErrInvalidSignature))
+		})
+	})
+
+	Context("when the bundle is not signed", func() {
+		BeforeEach(func() {
+			compressor.Config.PrivateKey = nil
+		})
+
+		It("returns an error", func() {
+			err := parcello.VerifyResource(parcello.BinaryResource(bundle.Body), publicKey)
+			Expect(err).To(Equal(parcello.ErrUnsigned))
+		})
+	})
+
+	Describe("ResourceManager", func() {
+		It("adds the verified bundle", func() {
+			manager := &parcello.ResourceManager{PublicKey: publicKey}
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			data, err := manager.ReadFile("/index.html")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("<html></html>"))
+		})
+
+		Context("when the bundle is tampered", func() {
+			It("refuses the bundle", func() {
+				body := tamper(bundle.Body, "index.html", "<html>tampered</html>")
+
+				manager := &parcello.ResourceManager{PublicKey: publicKey}
+				Expect(manager.Add(parcello.BinaryResource(body))).To(Equal(parcello.ErrInvalidSignature))
+
+				_, err := manager.Stat("/index.html")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the bundles are verified after they are added", func() {
+			It("reports the tampered bundle", func() {
+				manager := &parcello.ResourceManager{}
+				Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+				Expect(manager.Verify(publicKey)).To(Succeed())
+
+				manager = &parcello.ResourceManager{}
+				Expect(manager.Add(parcello.BinaryResource(tamper(bundle.Body, "css/app.css", "")))).To(Succeed())
+				Expect(manager.Verify(publicKey)).To(Equal(parcello.ErrInvalidSignature))
+			})
+
+			It("refuses to serve the resources when the verification fails", func() {
+				manager := &parcello.ResourceManager{}
+				Expect(manager.Add(parcello.BinaryResource(tamper(bundle.Body, "css/app.css", "")))).To(Succeed())
+
+				dir, err := manager.Dir("/css")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(manager.Verify(publicKey)).To(Equal(parcello.ErrInvalidSignature))
+
+				_, err = manager.ReadFile("/index.html")
+				Expect(err).To(MatchError("open /index.html: Invalid bundle signature"))
+
+				_, err = manager.Stat("/css/app.css")
+				Expect(errors.Is(err, parcello.ErrInvalidSignature)).To(BeTrue())
+
+				_, err = dir.Open("/app.css")
+				Expect(errors.Is(err, parcello.ErrInvalidSignature)).To(BeTrue())
+
+				Expect(manager.Walk("/", func(string, os.FileInfo, error) error { return nil })).To(HaveOccurred())
+				Expect(manager.Err()).To(MatchError("Invalid bundle: Invalid bundle signature"))
+			})
+
+			It("does not verify the file systems", func() {
+				manager := &parcello.ResourceManager{}
+				Expect(manager.Add(parcello.FSResource(fstest.MapFS{
+					"index.html": &fstest.MapFile{Data: []byte("<html></html>")},
+				}))).To(Succeed())
+
+				Expect(manager.Verify(publicKey)).To(Succeed())
+
+				_, err := manager.ReadFile("/index.html")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("verifies the bundles that are added afterwards", func() {
+				manager := &parcello.ResourceManager{}
+				Expect(manager.Verify(publicKey)).To(Succeed())
+
+				body := tamper(bundle.Body, "index.html", "<html>tampered</html>")
+				Expect(manager.Add(parcello.BinaryResource(body))).To(Equal(parcello.ErrInvalidSignature))
+			})
+		})
+	})
+
+	Describe("ParsePrivateKey", func() {
+		It("parses the PEM encoded key", func() {
+			data, err := x509.MarshalPKCS8PrivateKey(privateKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			key, err := parcello.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(Equal(privateKey))
+		})
+
+		Context("when the key is not PEM encoded", func() {
+			It("returns an error", func() {
+				_, err := parcello.ParsePrivateKey([]byte("key"))
+				Expect(err).To(MatchError("Invalid PEM encoded private key"))
+			})
+		})
+	})
+
+	Describe("ParsePublicKey", func() {
+		It("parses the PEM encoded key", func() {
+			data, err := x509.MarshalPKIXPublicKey(publicKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			key, err := parcello.ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: data}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(Equal(publicKey))
+		})
+
+		Context("when the key is not PEM encoded", func() {
+			It("returns an error", func() {
+				_, err := parcello.ParsePublicKey([]byte("key"))
+				Expect(err).To(MatchError("Invalid PEM encoded public key"))
+			})
+		})
+	})
+})
+
+// tamper replaces the content of the named entry and keeps the signature
+func tamper(body []byte, name, content string) []byte {
+	return rewrite(body, func(header *zip.FileHeader, data []byte) []byte {
+		if header.Name == name {
+			return []byte(content)
+		}
+
+		return data
+	})
+}
+
+// rewrite copies the entries of the bundle, which headers and content can be
+// modified by fn, and keeps the signature of the bundle
+func rewrite(body []byte, fn func(header *zip.FileHeader, data []byte) []byte) []byte {
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	Expect(err).NotTo(HaveOccurred())
+
+	buffer := &bytes.Buffer{}
+	writer := zip.NewWriter(buffer)
+
+	for _, file := range reader.File {
+		header := &zip.FileHeader{
+			Name:    file.Name,
+			Method:  file.Method,
+			Comment: file.Comment,
+		}
+
+		header.SetMode(file.Mode())
+
+		source, err := file.Open()
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := ioutil.ReadAll(source)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Close()).To(Succeed())
+
+		data = fn(header, data)
+
+		entry, err := writer.CreateHeader(header)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = entry.Write(data)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Expect(writer.SetComment(reader.Comment)).To(Succeed())
+	Expect(writer.Close()).To(Succeed())
+
+	return buffer.Bytes()
+}
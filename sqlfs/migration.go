@@ -0,0 +1,160 @@
+// Package sqlfs loads SQL migrations and named queries from a parcello file
+// system and runs them with database/sql.
+package sqlfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/phogolabs/parcello"
+)
+
+// migrationPattern matches the names of the migration files (for instance
+// 001_init.up.sql)
+var migrationPattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the database schema
+type Migration struct {
+	// Version is the numeric prefix of the file name (for instance 001)
+	Version string
+	// Name is the description of the migration (for instance init)
+	Name string
+	// Up contains the statements that apply the migration
+	Up string
+	// Down contains the statements that revert the migration (empty if the
+	// migration cannot be reverted)
+	Down string
+}
+
+// String returns the version and the name of the migration
+func (m *Migration) String() string {
+	return m.Version + "_" + m.Name
+}
+
+// LoadMigrations loads the migrations of the directory sorted by their
+// versions. Every migration consists of an up file and an optional down
+// file (for instance 001_init.up.sql and 001_init.down.sql).
+func LoadMigrations(fileSystem parcello.FileSystem, dir string) ([]*Migration, error) {
+	names, err := readDir(fileSystem, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := map[string]*Migration{}
+
+	for _, name := range names {
+		if path.Ext(name) != ".sql" {
+			continue
+		}
+
+		match := migrationPattern.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("The migration file '%s' does not match 'version_name.up.sql' or 'version_name.down.sql'", name)
+		}
+
+		content, err := readFile(fileSystem, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		version, description, direction := match[1], match[2], match[3]
+
+		migration, ok := migrations[version]
+
+		switch {
+		case !ok:
+			migration = &Migration{Version: version, Name: description}
+			migrations[version] = migration
+		case migration.Name != description:
+			return nil, fmt.Errorf("The migration version '%s' is used by '%s' and '%s'", version, migration, version+"_"+description)
+		}
+
+		if direction == "up" {
+			migration.Up = content
+		} else {
+			migration.Down = content
+		}
+	}
+
+	items := []*Migration{}
+
+	for _, migration := range migrations {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("The migration '%s' does not have up statements", migration)
+		}
+
+		items = append(items, migration)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if version(items[i]) == version(items[j]) {
+			return items[i].Version < items[j].Version
+		}
+
+		return version(items[i]) < version(items[j])
+	})
+
+	for index := 1; index < len(items); index++ {
+		if prev, next := items[index-1], items[index]; version(prev) == version(next) {
+			return nil, fmt.Errorf("The migration version '%s' is used by '%s' and '%s'", next.Version, prev, next)
+		}
+	}
+
+	return items, nil
+}
+
+// version returns the numeric version of the migration
+func version(migration *Migration) uint64 {
+	value, _ := strconv.ParseUint(migration.Version, 10, 64)
+	return value
+}
+
+// readDir returns the sorted names of the files in the directory
+func readDir(fileSystem parcello.FileSystem, dir string) ([]string, error) {
+	file, err := fileSystem.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	infos, err := file.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// readFile returns the content of the named file
+func readFile(fileSystem parcello.FileSystem, name string) (string, error) {
+	file, err := fileSystem.OpenFile(filepath.FromSlash(name), os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,282 @@
+package sqlfs_test
+
+import (
+	"context"
+	"database/sql"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/parcello/sqlfs"
+)
+
+var _ = Describe("LoadMigrations", func() {
+	var fileSystem fstest.MapFS
+
+	BeforeEach(func() {
+		fileSystem = fstest.MapFS{
+			"migrations/002_add_email.up.sql":   &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+			"migrations/010_posts.up.sql":       &fstest.MapFile{Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+			"migrations/010_posts.down.sql":     &fstest.MapFile{Data: []byte("DROP TABLE posts;")},
+			"migrations/001_init.up.sql":        &fstest.MapFile{Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+			"migrations/001_init.down.sql":      &fstest.MapFile{Data: []byte("DROP TABLE users;")},
+			"migrations/README.md":              &fstest.MapFile{Data: []byte("# Migrations")},
+			"migrations/archive/000_old.up.sql": &fstest.MapFile{Data: []byte("SELECT 1;")},
+		}
+	})
+
+	It("loads the migrations sorted by their versions", func() {
+		migrations, err := sqlfs.LoadMigrations(parcello.FromFS(fileSystem), "migrations")
+		Expect(err).To(BeNil())
+		Expect(migrations).To(HaveLen(3))
+
+		Expect(migrations[0]).To(Equal(&sqlfs.Migration{
+			Version: "001",
+			Name:    "init",
+			Up:      "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);",
+			Down:    "DROP TABLE users;",
+		}))
+
+		Expect(migrations[1].String()).To(Equal("002_add_email"))
+		Expect(migrations[1].Down).To(BeEmpty())
+		Expect(migrations[2].String()).To(Equal("010_posts"))
+	})
+
+	Context("when the file name is invalid", func() {
+		It("returns an error", func() {
+			fileSystem["migrations/003-seed.sql"] = &fstest.MapFile{}
+
+			migrations, err := sqlfs.LoadMigrations(parcello.FromFS(fileSystem), "migrations")
+			Expect(err).To(MatchError("The migration file '003-seed.sql' does not match 'version_name.up.sql' or 'version_name.down.sql'"))
+			Expect(migrations).To(BeNil())
+		})
+	})
+
+	Context("when the version is used more than once", func() {
+		It("returns an error", func() {
+			fileSystem["migrations/2_seed.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+			migrations, err := sqlfs.LoadMigrations(parcello.FromFS(fileSystem), "migrations")
+			Expect(err).To(MatchError("The migration version '2' is used by '002_add_email' and '2_seed'"))
+			Expect(migrations).To(BeNil())
+		})
+	})
+
+	Context("when the migration does not have up file", func() {
+		It("returns an error", func() {
+			delete(fileSystem, "migrations/010_posts.up.sql")
+
+			migrations, err := sqlfs.LoadMigrations(parcello.FromFS(fileSystem), "migrations")
+			Expect(err).To(MatchError("The migration '010_posts' does not have up statements"))
+			Expect(migrations).To(BeNil())
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		It("returns an error", func() {
+			migrations, err := sqlfs.LoadMigrations(parcello.FromFS(fileSystem), "schema")
+			Expect(err).To(HaveOccurred())
+			Expect(migrations).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("Migrator", func() {
+	var (
+		ctx      context.Context
+		db       *sql.DB
+		migrator *sqlfs.Migrator
+	)
+
+	tables := func() []string {
+		rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+		Expect(err).To(BeNil())
+		defer rows.Close()
+
+		names := []string{}
+
+		for rows.Next() {
+			var name string
+			Expect(rows.Scan(&name)).To(Succeed())
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		ctx = context.Background()
+
+		db, err = sql.Open("sqlite3", ":memory:")
+		Expect(err).To(BeNil())
+
+		// every connection has its own in-memory database
+		db.SetMaxOpenConns(1)
+
+		migrations, err := sqlfs.LoadMigrations(parcello.FromFS(fstest.MapFS{
+			"001_init.up.sql":    &fstest.MapFile{Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+			"001_init.down.sql":  &fstest.MapFile{Data: []byte("DROP TABLE users;")},
+			"002_posts.up.sql":   &fstest.MapFile{Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);\nCREATE TABLE tags (id INTEGER PRIMARY KEY);")},
+			"002_posts.down.sql": &fstest.MapFile{Data: []byte("DROP TABLE tags;\nDROP TABLE posts;")},
+		}), "/")
+		Expect(err).To(BeNil())
+
+		migrator = &sqlfs.Migrator{
+			DB:         db,
+			Migrations: migrations,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+	})
+
+	It("applies the pending migrations", func() {
+		applied, err := migrator.Up(ctx)
+		Expect(err).To(BeNil())
+		Expect(applied).To(HaveLen(2))
+		Expect(tables()).To(Equal([]string{"posts", "schema_migrations", "tags", "users"}))
+
+		applied, err = migrator.Up(ctx)
+		Expect(err).To(BeNil())
+		Expect(applied).To(BeEmpty())
+
+		pending, err := migrator.Pending(ctx)
+		Expect(err).To(BeNil())
+		Expect(pending).To(BeEmpty())
+	})
+
+	It("reverts the last applied migration", func() {
+		_, err := migrator.Up(ctx)
+		Expect(err).To(BeNil())
+
+		reverted, err := migrator.Down(ctx)
+		Expect(err).To(BeNil())
+		Expect(reverted.String()).To(Equal("002_posts"))
+		Expect(tables()).To(Equal([]string{"schema_migrations", "users"}))
+
+		pending, err := migrator.Pending(ctx)
+		Expect(err).To(BeNil())
+		Expect(pending).To(HaveLen(1))
+		Expect(pending[0].String()).To(Equal("002_posts"))
+
+		reverted, err = migrator.Down(ctx)
+		Expect(err).To(BeNil())
+		Expect(reverted.String()).To(Equal("001_init"))
+
+		reverted, err = migrator.Down(ctx)
+		Expect(err).To(BeNil())
+		Expect(reverted).To(BeNil())
+	})
+
+	Context("when the table is configured", func() {
+		It("records the migrations in the table", func() {
+			migrator.Table = "migrations"
+
+			_, err := migrator.Up(ctx)
+			Expect(err).To(BeNil())
+			Expect(tables()).To(ContainElement("migrations"))
+			Expect(tables()).NotTo(ContainElement("schema_migrations"))
+		})
+	})
+
+	Context("when the table name is invalid", func() {
+		It("returns an error", func() {
+			migrator.Table = "migrations; DROP TABLE users"
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).To(MatchError("Invalid migration table name 'migrations; DROP TABLE users'"))
+			Expect(applied).To(BeNil())
+		})
+	})
+
+	Context("when the table name is qualified by a schema", func() {
+		It("records the migrations in the table", func() {
+			migrator.Table = "main.migrations"
+
+			_, err := migrator.Up(ctx)
+			Expect(err).To(BeNil())
+			Expect(tables()).To(ContainElement("migrations"))
+		})
+	})
+
+	Context("when the version contains a quote", func() {
+		It("passes the version as an argument", func() {
+			migrator.Migrations = []*sqlfs.Migration{
+				{Version: "001'", Name: "init", Up: "CREATE TABLE users (id INTEGER PRIMARY KEY);", Down: "DROP TABLE users;"},
+			}
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).To(BeNil())
+			Expect(applied).To(HaveLen(1))
+
+			reverted, err := migrator.Down(ctx)
+			Expect(err).To(BeNil())
+			Expect(reverted.Version).To(Equal("001'"))
+		})
+	})
+
+	Context("when the placeholder is configured", func() {
+		It("uses the placeholder in the statements", func() {
+			migrator.Placeholder = sqlfs.DollarPlaceholder
+
+			_, err := migrator.Up(ctx)
+			Expect(err).To(BeNil())
+
+			pending, err := migrator.Pending(ctx)
+			Expect(err).To(BeNil())
+			Expect(pending).To(BeEmpty())
+
+			reverted, err := migrator.Down(ctx)
+			Expect(err).To(BeNil())
+			Expect(reverted.String()).To(Equal("002_posts"))
+		})
+	})
+
+	Context("when the migration fails", func() {
+		It("rolls back the migration", func() {
+			migrator.Migrations = append(migrator.Migrations, &sqlfs.Migration{
+				Version: "003",
+				Name:    "broken",
+				Up:      "CREATE TABLE comments (id INTEGER PRIMARY KEY);\nINSERT INTO missing VALUES (1);",
+			})
+
+			applied, err := migrator.Up(ctx)
+			Expect(err).To(MatchError(HavePrefix("The migration '003_broken' failed")))
+			Expect(applied).To(HaveLen(2))
+			Expect(tables()).NotTo(ContainElement("comments"))
+
+			pending, err := migrator.Pending(ctx)
+			Expect(err).To(BeNil())
+			Expect(pending).To(HaveLen(1))
+		})
+	})
+
+	Context("when the migration cannot be reverted", func() {
+		It("returns an error", func() {
+			migrator.Migrations[1].Down = ""
+
+			_, err := migrator.Up(ctx)
+			Expect(err).To(BeNil())
+
+			reverted, err := migrator.Down(ctx)
+			Expect(err).To(MatchError("The migration '002_posts' cannot be reverted"))
+			Expect(reverted).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("DriverPlaceholder", func() {
+	It("returns the placeholder of the driver", func() {
+		Expect(sqlfs.DriverPlaceholder("postgres")(2)).To(Equal("$2"))
+		Expect(sqlfs.DriverPlaceholder("pgx")(1)).To(Equal("$1"))
+		Expect(sqlfs.DriverPlaceholder("sqlserver")(1)).To(Equal("@p1"))
+		Expect(sqlfs.DriverPlaceholder("godror")(3)).To(Equal(":3"))
+		Expect(sqlfs.DriverPlaceholder("mysql")(1)).To(Equal("?"))
+		Expect(sqlfs.DriverPlaceholder("sqlite3")(1)).To(Equal("?"))
+	})
+})
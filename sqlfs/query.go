@@ -0,0 +1,196 @@
+package sqlfs
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/phogolabs/parcello"
+)
+
+// namePattern matches the annotation that starts a named query (for instance
+// -- name: GetUser)
+var namePattern = regexp.MustCompile(`^\s*--\s*name\s*:\s*(\S+)\s*$`)
+
+// Query is a named SQL statement
+type Query struct {
+	// Name of the query (for instance GetUser)
+	Name string
+	// SQL contains the statement of the query
+	SQL string
+	// File is the path of the file that defines the query
+	File string
+}
+
+// Queries contains the named queries by their names
+type Queries map[string]*Query
+
+// LoadQueries loads the named queries of the SQL files in the directory and
+// its sub-directories. Every query starts with a '-- name: GetUser' comment
+// and ends with the next one or with the end of the file.
+func LoadQueries(fileSystem parcello.FileSystem, dir string) (Queries, error) {
+	queries := Queries{}
+
+	err := fileSystem.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(name) != ".sql" {
+			return err
+		}
+
+		content, err := readFile(fileSystem, name)
+		if err != nil {
+			return err
+		}
+
+		name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+		return queries.parse(name, content)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
+
+// Names returns the sorted names of the queries
+func (q Queries) Names() []string {
+	names := []string{}
+
+	for name := range q {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the statement of the named query
+func (q Queries) Lookup(name string) (string, error) {
+	query, ok := q[name]
+	if !ok {
+		return "", fmt.Errorf("The query '%s' does not exist", name)
+	}
+
+	return query.SQL, nil
+}
+
+// Exec executes the named query that does not return rows
+func (q Queries) Exec(ctx context.Context, runner Runner, name string, args ...interface{}) (sql.Result, error) {
+	statement, err := q.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.ExecContext(ctx, statement, args...)
+}
+
+// Query executes the named query that returns rows
+func (q Queries) Query(ctx context.Context, runner Runner, name string, args ...interface{}) (*sql.Rows, error) {
+	statement, err := q.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.QueryContext(ctx, statement, args...)
+}
+
+// QueryRow executes the named query that returns at most one row. The error
+// of a missing query is deferred until the row is scanned.
+func (q Queries) QueryRow(ctx context.Context, runner Runner, name string, args ...interface{}) *Row {
+	statement, err := q.Lookup(name)
+	if err != nil {
+		return &Row{err: err}
+	}
+
+	return &Row{row: runner.QueryRowContext(ctx, statement, args...)}
+}
+
+// parse adds the queries of the file
+func (q Queries) parse(file, content string) error {
+	var (
+		query   *Query
+		builder strings.Builder
+	)
+
+	flush := func() error {
+		if query == nil {
+			return nil
+		}
+
+		if query.SQL = strings.TrimSpace(builder.String()); query.SQL == "" {
+			return fmt.Errorf("The query '%s' in '%s' is empty", query.Name, file)
+		}
+
+		builder.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), len(content)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := namePattern.FindStringSubmatch(line); match != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+
+			if prev, ok := q[match[1]]; ok {
+				return fmt.Errorf("The query '%s' is defined in '%s' and '%s'", match[1], prev.File, file)
+			}
+
+			query = &Query{Name: match[1], File: path.Clean(file)}
+			q[query.Name] = query
+			continue
+		}
+
+		if query == nil {
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return fmt.Errorf("The statement in '%s' does not have a '-- name:' annotation", file)
+			}
+
+			continue
+		}
+
+		builder.WriteString(line)
+		builder.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+// Row is the result of QueryRow
+type Row struct {
+	row *sql.Row
+	err error
+}
+
+// Scan copies the columns of the row into the values pointed at by dest
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.row.Scan(dest...)
+}
+
+// Err returns the error of the query
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.row.Err()
+}
@@ -0,0 +1,116 @@
+package sqlfs_test
+
+import (
+	"context"
+	"database/sql"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/parcello/sqlfs"
+)
+
+var _ = Describe("LoadQueries", func() {
+	var fileSystem fstest.MapFS
+
+	BeforeEach(func() {
+		fileSystem = fstest.MapFS{
+			"queries/users.sql": &fstest.MapFile{Data: []byte(`-- Queries of the users
+
+-- name: InsertUser
+INSERT INTO users (id, name) VALUES (?, ?);
+
+-- name: GetUser
+-- returns a single user
+SELECT name
+FROM users
+WHERE id = ?;
+`)},
+			"queries/admin/stats.sql": &fstest.MapFile{Data: []byte("-- name: CountUsers\nSELECT COUNT(*) FROM users;\n")},
+			"queries/README.md":       &fstest.MapFile{Data: []byte("-- name: Ignored")},
+		}
+	})
+
+	It("loads the named queries", func() {
+		queries, err := sqlfs.LoadQueries(parcello.FromFS(fileSystem), "queries")
+		Expect(err).To(BeNil())
+		Expect(queries.Names()).To(Equal([]string{"CountUsers", "GetUser", "InsertUser"}))
+
+		Expect(queries["GetUser"]).To(Equal(&sqlfs.Query{
+			Name: "GetUser",
+			SQL:  "-- returns a single user\nSELECT name\nFROM users\nWHERE id = ?;",
+			File: "queries/users.sql",
+		}))
+
+		statement, err := queries.Lookup("CountUsers")
+		Expect(err).To(BeNil())
+		Expect(statement).To(Equal("SELECT COUNT(*) FROM users;"))
+	})
+
+	It("runs the named queries", func() {
+		ctx := context.Background()
+
+		db, err := sql.Open("sqlite3", ":memory:")
+		Expect(err).To(BeNil())
+		defer db.Close()
+
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+		Expect(err).To(BeNil())
+
+		queries, err := sqlfs.LoadQueries(parcello.FromFS(fileSystem), "queries")
+		Expect(err).To(BeNil())
+
+		_, err = queries.Exec(ctx, db, "InsertUser", 1, "jack")
+		Expect(err).To(BeNil())
+
+		var name string
+		Expect(queries.QueryRow(ctx, db, "GetUser", 1).Scan(&name)).To(Succeed())
+		Expect(name).To(Equal("jack"))
+
+		rows, err := queries.Query(ctx, db, "CountUsers")
+		Expect(err).To(BeNil())
+		defer rows.Close()
+
+		var count int
+		Expect(rows.Next()).To(BeTrue())
+		Expect(rows.Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+
+		err = queries.QueryRow(ctx, db, "DeleteUser", 1).Scan(&name)
+		Expect(err).To(MatchError("The query 'DeleteUser' does not exist"))
+	})
+
+	Context("when the query is defined more than once", func() {
+		It("returns an error", func() {
+			fileSystem["queries/more.sql"] = &fstest.MapFile{Data: []byte("-- name: GetUser\nSELECT 1;")}
+
+			queries, err := sqlfs.LoadQueries(parcello.FromFS(fileSystem), "queries")
+			Expect(err).To(MatchError("The query 'GetUser' is defined in 'queries/more.sql' and 'queries/users.sql'"))
+			Expect(queries).To(BeNil())
+		})
+	})
+
+	Context("when the query is empty", func() {
+		It("returns an error", func() {
+			fileSystem["queries/empty.sql"] = &fstest.MapFile{Data: []byte("-- name: Empty\n\n-- name: Other\nSELECT 1;")}
+
+			queries, err := sqlfs.LoadQueries(parcello.FromFS(fileSystem), "queries")
+			Expect(err).To(MatchError("The query 'Empty' in 'queries/empty.sql' is empty"))
+			Expect(queries).To(BeNil())
+		})
+	})
+
+	Context("when the statement does not have a name", func() {
+		It("returns an error", func() {
+			fileSystem["queries/anonymous.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+			queries, err := sqlfs.LoadQueries(parcello.FromFS(fileSystem), "queries")
+			Expect(err).To(MatchError("The statement in 'queries/anonymous.sql' does not have a '-- name:' annotation"))
+			Expect(queries).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,257 @@
+package sqlfs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	_ Runner = &sql.DB{}
+	_ Runner = &sql.Tx{}
+	_ Runner = &sql.Conn{}
+	_ DB     = &sql.DB{}
+	_ DB     = &sql.Conn{}
+)
+
+// MigrationTable is the default name of the table that contains the versions
+// of the applied migrations
+const MigrationTable = "schema_migrations"
+
+// tableRegexp matches the valid names of the migration table, optionally
+// qualified by a schema
+var tableRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Placeholder returns the placeholder of the n-th (starting from 1) argument
+// of a statement
+type Placeholder func(n int) string
+
+var (
+	// QuestionPlaceholder is used by MySQL and SQLite (?)
+	QuestionPlaceholder Placeholder = func(n int) string { return "?" }
+	// DollarPlaceholder is used by PostgreSQL ($1)
+	DollarPlaceholder Placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	// AtPlaceholder is used by SQL Server (@p1)
+	AtPlaceholder Placeholder = func(n int) string { return fmt.Sprintf("@p%d", n) }
+	// ColonPlaceholder is used by Oracle (:1)
+	ColonPlaceholder Placeholder = func(n int) string { return fmt.Sprintf(":%d", n) }
+)
+
+// DriverPlaceholder returns the placeholder of the driver with given name (as
+// registered in database/sql). It returns QuestionPlaceholder for the
+// unknown drivers.
+func DriverPlaceholder(driver string) Placeholder {
+	switch strings.ToLower(driver) {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return DollarPlaceholder
+	case "sqlserver", "mssql":
+		return AtPlaceholder
+	case "oracle", "godror", "goracle", "oci8":
+		return ColonPlaceholder
+	default:
+		return QuestionPlaceholder
+	}
+}
+
+// Runner executes SQL statements. It is implemented by *sql.DB, *sql.Tx and
+// *sql.Conn.
+type Runner interface {
+	// ExecContext executes a query without returning any rows
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// QueryContext executes a query that returns rows
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	// QueryRowContext executes a query that is expected to return at most one row
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DB is a Runner that starts transactions. It is implemented by *sql.DB and
+// *sql.Conn.
+type DB interface {
+	Runner
+	// BeginTx starts a transaction
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Migrator applies and reverts the migrations. Every migration runs in its
+// own transaction together with the update of the migration table.
+type Migrator struct {
+	// DB is the migrated database
+	DB DB
+	// Migrations are the known migrations sorted by their versions
+	Migrations []*Migration
+	// Table is the name of the migration table (MigrationTable by default).
+	// It may be qualified by a schema.
+	Table string
+	// Placeholder is the placeholder of the statement arguments used by the
+	// driver (QuestionPlaceholder by default, see DriverPlaceholder)
+	Placeholder Placeholder
+}
+
+// Up applies the pending migrations and returns them
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := m.table()
+	if err != nil {
+		return nil, err
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (version) VALUES (%s)", table, m.placeholder(1))
+	applied := []*Migration{}
+
+	for _, migration := range pending {
+		if err := m.run(ctx, migration, migration.Up, insert); err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the last applied migration and returns it. It returns nil if
+// no migration has been applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	versions, err := m.versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := m.table()
+	if err != nil {
+		return nil, err
+	}
+
+	remove := fmt.Sprintf("DELETE FROM %s WHERE version = %s", table, m.placeholder(1))
+
+	for index := len(m.Migrations) - 1; index >= 0; index-- {
+		migration := m.Migrations[index]
+
+		if !versions[migration.Version] {
+			continue
+		}
+
+		if migration.Down == "" {
+			return nil, fmt.Errorf("The migration '%s' cannot be reverted", migration)
+		}
+
+		if err := m.run(ctx, migration, migration.Down, remove); err != nil {
+			return nil, err
+		}
+
+		return migration, nil
+	}
+
+	return nil, nil
+}
+
+// Pending returns the migrations that have not been applied
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	versions, err := m.versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*Migration{}
+
+	for _, migration := range m.Migrations {
+		if !versions[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// run executes the statements of the migration and updates the migration
+// table with its version in a transaction
+func (m *Migrator) run(ctx context.Context, migration *Migration, statements, update string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return rollback(tx, fmt.Errorf("The migration '%s' failed: %v", migration, err))
+	}
+
+	if _, err := tx.ExecContext(ctx, update, migration.Version); err != nil {
+		return rollback(tx, err)
+	}
+
+	return tx.Commit()
+}
+
+// rollback rolls back the transaction and returns given error, which is
+// combined with the error of the rollback if it fails
+func rollback(tx *sql.Tx, err error) error {
+	if rErr := tx.Rollback(); rErr != nil {
+		return fmt.Errorf("%v (rollback failed: %v)", err, rErr)
+	}
+
+	return err
+}
+
+// versions returns the versions of the applied migrations. The migration
+// table is created if it does not exist.
+func (m *Migrator) versions(ctx context.Context) (map[string]bool, error) {
+	table, err := m.table()
+	if err != nil {
+		return nil, err
+	}
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version VARCHAR(64) NOT NULL PRIMARY KEY)", table)
+
+	if _, err := m.DB.ExecContext(ctx, create); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s", table))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions := map[string]bool{}
+
+	for rows.Next() {
+		var version string
+
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		versions[version] = true
+	}
+
+	return versions, rows.Err()
+}
+
+// table returns the name of the migration table. The name is interpolated in
+// the statements, so it has to be a valid identifier.
+func (m *Migrator) table() (string, error) {
+	if m.Table == "" {
+		return MigrationTable, nil
+	}
+
+	if !tableRegexp.MatchString(m.Table) {
+		return "", fmt.Errorf("Invalid migration table name '%s'", m.Table)
+	}
+
+	return m.Table, nil
+}
+
+func (m *Migrator) placeholder(n int) string {
+	if m.Placeholder == nil {
+		return QuestionPlaceholder(n)
+	}
+
+	return m.Placeholder(n)
+}
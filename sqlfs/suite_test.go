@@ -0,0 +1,13 @@
+package sqlfs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSQLFS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SQLFS Suite")
+}
@@ -0,0 +1,302 @@
+package parcello
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxLinks is the maximum number of symbolic links that are followed while a
+// path is resolved
+const maxLinks = 40
+
+var (
+	// ErrTooManyLinks is returned if the resolution of a path follows too
+	// many symbolic links (usually because of a cycle)
+	ErrTooManyLinks = errors.New("Too many levels of symbolic links")
+	// ErrNotSymlink is returned if the file under operation is not a symbolic link
+	ErrNotSymlink = errors.New("Not a symbolic link")
+)
+
+// SymlinkPolicy determines how the compressor handles symbolic links
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow bundles the targets of the symbolic links as regular
+	// files and directories (default)
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkPreserve bundles the symbolic links as symlink entries that are
+	// resolved by the ResourceManager. The links must point inside the
+	// resource directory.
+	SymlinkPreserve SymlinkPolicy = "preserve"
+	// SymlinkError fails the compression if a symbolic link is found
+	SymlinkError SymlinkPolicy = "error"
+)
+
+// LinkFileSystem is implemented by the file systems that support symbolic
+// links
+type LinkFileSystem interface {
+	// Lstat returns a FileInfo describing the named file. If the file is a
+	// symbolic link, the returned FileInfo describes the link.
+	Lstat(name string) (os.FileInfo, error)
+	// Readlink returns the destination of the named symbolic link
+	Readlink(name string) (string, error)
+}
+
+// isSymlink returns true if the file info describes a symbolic link
+func isSymlink(info os.FileInfo) bool {
+	return info != nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// symlink applies the policy to the symbolic link. It returns the file info
+// that should be used for the link: the info of the target if the link is
+// followed or the info of the link if it is preserved.
+func (cfg *CompressorConfig) symlink(fileSystem FileSystem, name string, info os.FileInfo) (os.FileInfo, error) {
+	switch cfg.Symlinks {
+	case "", SymlinkFollow:
+		return follow(fileSystem, name, info)
+	case SymlinkPreserve:
+		if _, err := readlink(fileSystem, name); err != nil {
+			return nil, err
+		}
+
+		return info, nil
+	case SymlinkError:
+		return nil, fmt.Errorf("The resource '%s' is a symbolic link", name)
+	default:
+		return nil, fmt.Errorf("Unsupported symbolic link policy '%s'", cfg.Symlinks)
+	}
+}
+
+// follow returns the info of the link target. It fails if the target is a
+// parent directory of the link, because following it would never end.
+func follow(fileSystem FileSystem, name string, info os.FileInfo) (os.FileInfo, error) {
+	target, err := stat(fileSystem, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !target.IsDir() {
+		return &linkInfo{FileInfo: target, name: info.Name()}, nil
+	}
+
+	for dir := slashPath(name); dir != "."; {
+		dir = path.Dir(dir)
+
+		parent, err := stat(fileSystem, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if os.SameFile(parent, target) {
+			return nil, fmt.Errorf("The symbolic link '%s' creates a cycle", name)
+		}
+	}
+
+	return &linkInfo{FileInfo: target, name: info.Name()}, nil
+}
+
+// walkLink walks the directory that a followed symbolic link points to. The
+// file system walk does not descend into symbolic links on its own.
+func walkLink(fileSystem FileSystem, name string, fn filepath.WalkFunc) error {
+	dir, err := fileSystem.Open(name)
+	if err != nil {
+		return err
+	}
+
+	infos, err := dir.Readdir(-1)
+	dir.Close()
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	for _, info := range infos {
+		child := filepath.Join(name, info.Name())
+
+		err := fn(child, info, nil)
+
+		switch {
+		case err == filepath.SkipDir && info.IsDir():
+			continue
+		case err != nil:
+			return err
+		}
+
+		if info.IsDir() {
+			if err := walkLink(fileSystem, child, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readlink returns the destination of the symbolic link. The destination
+// must be relative and it must not leave the root of the file system.
+func readlink(fileSystem FileSystem, name string) (string, error) {
+	links, ok := fileSystem.(LinkFileSystem)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: ErrNotSupported}
+	}
+
+	target, err := links.Readlink(name)
+	if err != nil {
+		return "", err
+	}
+
+	target = filepath.ToSlash(target)
+	resolved := path.Join(path.Dir(slashPath(name)), target)
+
+	if path.IsAbs(target) || resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return "", fmt.Errorf("The symbolic link '%s' points outside of the resources", name)
+	}
+
+	return target, nil
+}
+
+// stat returns the info of the named file following the symbolic links
+func stat(fileSystem FileSystem, name string) (os.FileInfo, error) {
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return file.Stat()
+}
+
+// linkInfo is the info of a followed symbolic link. It has the name of the
+// link and the attributes of the target.
+type linkInfo struct {
+	os.FileInfo
+	name string
+}
+
+// Name returns the name of the link
+func (info *linkInfo) Name() string {
+	return info.name
+}
+
+// resolve returns the path of the named resource with all symbolic links
+// resolved. The last element of the path is not resolved, unless follow is
+// true. It fails if the manager refuses to serve the resources.
+func (m *ResourceManager) resolve(name string, follow bool) ([]string, error) {
+	if err := m.health().refusal(); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	parts := split(name)
+
+	if m.root == nil {
+		return parts, nil
+	}
+
+	for hops := 0; ; {
+		node := m.root
+		resolved := true
+
+		for index, part := range parts {
+			var child *Node
+
+			for _, item := range node.Children {
+				if item.Name == part {
+					child = item
+					break
+				}
+			}
+
+			if child == nil {
+				return parts, nil
+			}
+
+			if child.Link == "" || (index == len(parts)-1 && !follow) {
+				node = child
+				continue
+			}
+
+			if hops++; hops > maxLinks {
+				return nil, &os.PathError{Op: "open", Path: name, Err: ErrTooManyLinks}
+			}
+
+			target := append(append([]string{}, parts[:index]...), child.Link)
+			target = append(target, parts[index+1:]...)
+
+			parts = splitSlash(path.Join(target...))
+			resolved = false
+			break
+		}
+
+		if resolved {
+			return parts, nil
+		}
+	}
+}
+
+// splitSlash splits the clean slash-separated path into its elements
+func splitSlash(name string) []string {
+	parts := []string{}
+
+	for _, part := range strings.Split(name, "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// Lstat returns a FileInfo describing the named resource. If the resource is
+// a symbolic link, the returned FileInfo describes the link.
+func (m *ResourceManager) Lstat(name string) (os.FileInfo, error) {
+	parts, err := m.resolve(name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, node := find(parts, nil, m.root); node != nil {
+		return &ResourceFileInfo{Node: node}, nil
+	}
+
+	return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+}
+
+// Readlink returns the destination of the named symbolic link
+func (m *ResourceManager) Readlink(name string) (string, error) {
+	parts, err := m.resolve(name, false)
+	if err != nil {
+		return "", err
+	}
+
+	_, node := find(parts, nil, m.root)
+
+	switch {
+	case node == nil:
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	case node.Link == "":
+		return "", &os.PathError{Op: "readlink", Path: name, Err: ErrNotSymlink}
+	default:
+		return node.Link, nil
+	}
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, the returned FileInfo describes the link.
+func (d Dir) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(filepath.Join(string(d), name))
+}
+
+// Readlink returns the destination of the named symbolic link
+func (d Dir) Readlink(name string) (string, error) {
+	return os.Readlink(filepath.Join(string(d), name))
+}
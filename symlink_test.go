@@ -0,0 +1,269 @@
+package parcello_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Symlinks", func() {
+	var (
+		dir        string
+		compressor *parcello.ZipCompressor
+	)
+
+	compress := func() (*parcello.Bundle, error) {
+		return compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.Dir(dir),
+		})
+	}
+
+	names := func(bundle *parcello.Bundle) []string {
+		reader, err := zip.NewReader(bytes.NewReader(bundle.Body), int64(len(bundle.Body)))
+		Expect(err).To(BeNil())
+
+		items := []string{}
+
+		for _, file := range reader.File {
+			items = append(items, file.Name)
+		}
+
+		sort.Strings(items)
+		return items
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "parcello")
+		Expect(err).To(BeNil())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "shared"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "shared", "schema.sql"), []byte("CREATE TABLE users;"), 0600)).To(Succeed())
+		Expect(os.Symlink("shared/schema.sql", filepath.Join(dir, "schema.sql"))).To(Succeed())
+		Expect(os.Symlink("shared", filepath.Join(dir, "scripts"))).To(Succeed())
+
+		compressor = &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   GinkgoWriter,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("follows the symbolic links by default", func() {
+		bundle, err := compress()
+		Expect(err).To(BeNil())
+		Expect(names(bundle)).To(Equal([]string{
+			"schema.sql",
+			"scripts/schema.sql",
+			"shared/schema.sql",
+		}))
+
+		manager := &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+		info, err := manager.Lstat("schema.sql")
+		Expect(err).To(BeNil())
+		Expect(info.Mode() & os.ModeSymlink).To(BeZero())
+
+		data, err := manager.ReadFile("scripts/schema.sql")
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("CREATE TABLE users;"))
+	})
+
+	Context("when the link creates a cycle", func() {
+		It("returns an error", func() {
+			Expect(os.Symlink("..", filepath.Join(dir, "shared", "parent"))).To(Succeed())
+
+			bundle, err := compress()
+			// scripts is walked before shared and it links to the same directory
+			Expect(err).To(MatchError("The symbolic link 'scripts/parent' creates a cycle"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Context("when the policy is preserve", func() {
+		BeforeEach(func() {
+			compressor.Config.Symlinks = parcello.SymlinkPreserve
+		})
+
+		It("bundles the symbolic links", func() {
+			bundle, err := compress()
+			Expect(err).To(BeNil())
+			Expect(names(bundle)).To(Equal([]string{
+				"schema.sql",
+				"scripts",
+				"shared/schema.sql",
+			}))
+
+			manager := &parcello.ResourceManager{}
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			info, err := manager.Lstat("scripts")
+			Expect(err).To(BeNil())
+			Expect(info.Mode() & os.ModeSymlink).NotTo(BeZero())
+
+			target, err := manager.Readlink("scripts")
+			Expect(err).To(BeNil())
+			Expect(target).To(Equal("shared"))
+
+			info, err = manager.Stat("scripts")
+			Expect(err).To(BeNil())
+			Expect(info.IsDir()).To(BeTrue())
+
+			data, err := manager.ReadFile("scripts/schema.sql")
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("CREATE TABLE users;"))
+
+			data, err = manager.ReadFile("schema.sql")
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal("CREATE TABLE users;"))
+		})
+
+		It("preserves the symbolic links in reproducible mode", func() {
+			compressor.Config.Reproducible = true
+
+			bundle, err := compress()
+			Expect(err).To(BeNil())
+
+			manager := &parcello.ResourceManager{}
+			Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+			target, err := manager.Readlink("schema.sql")
+			Expect(err).To(BeNil())
+			Expect(target).To(Equal("shared/schema.sql"))
+		})
+
+		Context("when the link points outside of the resources", func() {
+			It("returns an error", func() {
+				Expect(os.Symlink("../etc/passwd", filepath.Join(dir, "passwd"))).To(Succeed())
+
+				bundle, err := compress()
+				Expect(err).To(MatchError("The symbolic link 'passwd' points outside of the resources"))
+				Expect(bundle).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the policy is error", func() {
+		It("returns an error", func() {
+			compressor.Config.Symlinks = parcello.SymlinkError
+
+			bundle, err := compress()
+			Expect(err).To(MatchError("The resource 'schema.sql' is a symbolic link"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Context("when the policy is not supported", func() {
+		It("returns an error", func() {
+			compressor.Config.Symlinks = "copy"
+
+			bundle, err := compress()
+			Expect(err).To(MatchError("Unsupported symbolic link policy 'copy'"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+
+	Context("when the resources are embedded by go:embed", func() {
+		It("returns an error", func() {
+			compressor := &parcello.EmbedCompressor{
+				Config: &parcello.CompressorConfig{
+					Logger:   GinkgoWriter,
+					Filename: "bundle",
+					Recurive: true,
+				},
+			}
+
+			bundle, err := compressor.Compress(&parcello.CompressorContext{
+				FileSystem: parcello.Dir(dir),
+			})
+
+			Expect(err).To(MatchError("The resource 'schema.sql' is a symbolic link"))
+			Expect(bundle).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("ResourceManager symbolic links", func() {
+	var manager *parcello.ResourceManager
+
+	BeforeEach(func() {
+		buffer := &bytes.Buffer{}
+		writer := zip.NewWriter(buffer)
+
+		add := func(name, content string, mode os.FileMode) {
+			header := &zip.FileHeader{Name: name, Method: zip.Store}
+			header.SetMode(mode)
+
+			entry, err := writer.CreateHeader(header)
+			Expect(err).To(BeNil())
+
+			_, err = entry.Write([]byte(content))
+			Expect(err).To(BeNil())
+		}
+
+		add("docs/readme.txt", "hello", 0644)
+		add("readme.txt", "docs/readme.txt", os.ModeSymlink|0777)
+		add("loop", "loop", os.ModeSymlink|0777)
+		add("dangling", "missing.txt", os.ModeSymlink|0777)
+
+		Expect(writer.Close()).To(Succeed())
+
+		manager = &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(buffer.Bytes()))).To(Succeed())
+	})
+
+	It("walks the symbolic links without following them", func() {
+		links := []string{}
+
+		err := manager.Walk("/", func(path string, info os.FileInfo, err error) error {
+			if info.Mode()&os.ModeSymlink != 0 {
+				links = append(links, path)
+			}
+
+			return err
+		})
+
+		Expect(err).To(BeNil())
+		Expect(links).To(ConsistOf("/readme.txt", "/loop", "/dangling"))
+	})
+
+	Context("when the link is not a symbolic link", func() {
+		It("returns an error", func() {
+			_, err := manager.Readlink("docs/readme.txt")
+			Expect(err).To(MatchError("readlink docs/readme.txt: Not a symbolic link"))
+		})
+	})
+
+	Context("when the link points to itself", func() {
+		It("returns an error", func() {
+			_, err := manager.Open("loop")
+			Expect(err).To(MatchError("open loop: Too many levels of symbolic links"))
+		})
+	})
+
+	Context("when the link is dangling", func() {
+		It("returns an error", func() {
+			_, err := manager.Open("dangling")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			info, err := manager.Lstat("dangling")
+			Expect(err).To(BeNil())
+			Expect(info.Mode() & os.ModeSymlink).NotTo(BeZero())
+		})
+	})
+})
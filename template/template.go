@@ -0,0 +1,417 @@
+// Package template parses the html/template and text/template files of a
+// parcello file system.
+//
+// Every page is parsed together with the layouts and the partials into its
+// own template set, so the pages can define the blocks of the layouts without
+// overriding each other. The templates are named by their paths relative to
+// the template directory (for instance layouts/base.html).
+package template
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/phogolabs/parcello"
+)
+
+// FuncMap is the map of the functions available in the templates
+type FuncMap map[string]interface{}
+
+// Config controls how the templates are parsed
+type Config struct {
+	// Dir is the directory of the templates (the root by default)
+	Dir string
+	// Extensions of the template files (by default .html, .tmpl and .gohtml)
+	Extensions []string
+	// Layouts is the directory of the layouts relative to Dir (layouts by
+	// default)
+	Layouts string
+	// Partials is the directory of the partials relative to Dir (partials by
+	// default)
+	Partials string
+	// Layout is the name of the layout that renders the pages (for instance
+	// layouts/base.html). The pages are executed directly if it is empty.
+	Layout string
+	// Funcs are the functions available in the templates
+	Funcs FuncMap
+	// Text parses the templates with text/template instead of html/template
+	Text bool
+	// Reload re-parses the templates when they change. It is enabled for
+	// parcello.Dir file systems, which are used in development.
+	Reload bool
+}
+
+// Set is a collection of parsed pages
+type Set struct {
+	fileSystem parcello.FileSystemManager
+	config     *Config
+	rw         sync.RWMutex
+	pages      map[string]parser
+	names      []string
+	stale      bool
+	cancel     context.CancelFunc
+}
+
+// New parses the templates of the file system. The set must be closed if
+// the templates are reloaded.
+func New(fileSystem parcello.FileSystemManager, cfg *Config) (*Set, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	if cfg.Dir != "" {
+		dir, err := fileSystem.Dir(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+
+		fileSystem = dir
+	}
+
+	set := &Set{
+		fileSystem: fileSystem,
+		config:     cfg,
+		cancel:     func() {},
+	}
+
+	if err := set.parse(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := fileSystem.(parcello.Dir); ok || cfg.Reload {
+		if err := set.watch(); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// Execute applies the named page to the data object and writes the output
+// to w. The page is rendered by the layout if it is configured.
+func (s *Set) Execute(w io.Writer, name string, data interface{}) error {
+	page, err := s.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if layout := s.config.Layout; layout != "" {
+		return page.execute(w, layout, data)
+	}
+
+	return page.execute(w, strings.TrimPrefix(name, "/"), data)
+}
+
+// Names returns the sorted names of the pages
+func (s *Set) Names() []string {
+	// the error is reported by the next execution
+	_ = s.reload()
+
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	names := []string{}
+
+	for name := range s.pages {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Close stops reloading the templates
+func (s *Set) Close() error {
+	s.cancel()
+	return nil
+}
+
+// lookup returns the named page
+func (s *Set) lookup(name string) (parser, error) {
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	page, ok := s.pages[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return nil, fmt.Errorf("The template '%s' does not exist", name)
+	}
+
+	return page, nil
+}
+
+// reload re-parses the templates if they have changed
+func (s *Set) reload() error {
+	s.rw.Lock()
+	stale := s.stale
+	s.stale = false
+	s.rw.Unlock()
+
+	if !stale {
+		return nil
+	}
+
+	if err := s.parse(); err != nil {
+		// the templates are parsed again by the next call
+		s.rw.Lock()
+		s.stale = true
+		s.rw.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// watch marks the templates as stale when they change
+func (s *Set) watch() error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := s.fileSystem.Watch(ctx, "")
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	s.cancel = cancel
+
+	go func() {
+		for event := range events {
+			if s.changed(event) {
+				s.rw.Lock()
+				s.stale = true
+				s.rw.Unlock()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// changed returns true if the event affects the templates. The templates
+// cannot be trusted if the watcher has failed. The removed or renamed
+// directories are reported without the templates they contain.
+func (s *Set) changed(event parcello.Event) bool {
+	if event.Err != nil || s.template(event.Name) {
+		return true
+	}
+
+	if event.Op&(parcello.Remove|parcello.Rename) == 0 {
+		return false
+	}
+
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	for _, name := range s.names {
+		if name == event.Name || strings.HasPrefix(name, event.Name+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parse parses the pages with the layouts and the partials
+func (s *Set) parse() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	base := s.parser()
+	pages := []string{}
+
+	for _, name := range files {
+		if !s.shared(name) {
+			pages = append(pages, name)
+			continue
+		}
+
+		if err := s.add(base, name); err != nil {
+			return err
+		}
+	}
+
+	set := map[string]parser{}
+
+	for _, name := range pages {
+		page, err := base.clone()
+		if err != nil {
+			return err
+		}
+
+		if err := s.add(page, name); err != nil {
+			return err
+		}
+
+		set[name] = page
+	}
+
+	s.rw.Lock()
+	s.pages = set
+	s.names = files
+	s.rw.Unlock()
+
+	return nil
+}
+
+// files returns the sorted names of the template files
+func (s *Set) files() ([]string, error) {
+	files := []string{}
+
+	err := s.fileSystem.Walk("/", func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name = strings.TrimPrefix(filepath.ToSlash(name), "/")
+
+		if !info.IsDir() && s.template(name) {
+			files = append(files, name)
+		}
+
+		return nil
+	})
+
+	sort.Strings(files)
+	return files, err
+}
+
+// add parses the named file into the template set
+func (s *Set) add(set parser, name string) error {
+	file, err := s.fileSystem.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	return set.parse(name, string(data))
+}
+
+// template returns true if the file is a template
+func (s *Set) template(name string) bool {
+	extensions := s.config.Extensions
+
+	if len(extensions) == 0 {
+		extensions = []string{".html", ".tmpl", ".gohtml"}
+	}
+
+	ext := path.Ext(name)
+
+	for _, extension := range extensions {
+		if ext == extension {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shared returns true if the file is a layout or a partial
+func (s *Set) shared(name string) bool {
+	for _, dir := range []string{
+		dirOrDefault(s.config.Layouts, "layouts"),
+		dirOrDefault(s.config.Partials, "partials"),
+	} {
+		if strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parser returns an empty template set with the functions
+func (s *Set) parser() parser {
+	if s.config.Text {
+		return &textParser{
+			template: texttemplate.New("").Funcs(texttemplate.FuncMap(s.config.Funcs)),
+		}
+	}
+
+	return &htmlParser{
+		template: htmltemplate.New("").Funcs(htmltemplate.FuncMap(s.config.Funcs)),
+	}
+}
+
+func dirOrDefault(dir, fallback string) string {
+	if dir = strings.Trim(filepath.ToSlash(dir), "/"); dir == "" {
+		return fallback
+	}
+
+	return dir
+}
+
+// parser is a set of templates of html/template or text/template
+type parser interface {
+	// parse parses the named template
+	parse(name, text string) error
+	// clone returns a copy of the set
+	clone() (parser, error)
+	// execute applies the named template to the data
+	execute(w io.Writer, name string, data interface{}) error
+}
+
+type htmlParser struct {
+	template *htmltemplate.Template
+}
+
+func (p *htmlParser) parse(name, text string) error {
+	_, err := p.template.New(name).Parse(text)
+	return err
+}
+
+func (p *htmlParser) clone() (parser, error) {
+	template, err := p.template.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return &htmlParser{template: template}, nil
+}
+
+func (p *htmlParser) execute(w io.Writer, name string, data interface{}) error {
+	return p.template.ExecuteTemplate(w, name, data)
+}
+
+type textParser struct {
+	template *texttemplate.Template
+}
+
+func (p *textParser) parse(name, text string) error {
+	_, err := p.template.New(name).Parse(text)
+	return err
+}
+
+func (p *textParser) clone() (parser, error) {
+	template, err := p.template.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return &textParser{template: template}, nil
+}
+
+func (p *textParser) execute(w io.Writer, name string, data interface{}) error {
+	return p.template.ExecuteTemplate(w, name, data)
+}
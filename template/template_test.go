@@ -0,0 +1,197 @@
+package template_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+	"github.com/phogolabs/parcello/template"
+)
+
+var _ = Describe("Set", func() {
+	var (
+		fileSystem fstest.MapFS
+		config     *template.Config
+	)
+
+	render := func(set *template.Set, name string, data interface{}) string {
+		buffer := &bytes.Buffer{}
+		Expect(set.Execute(buffer, name, data)).To(Succeed())
+		return buffer.String()
+	}
+
+	BeforeEach(func() {
+		fileSystem = fstest.MapFS{
+			"views/layouts/base.html":  &fstest.MapFile{Data: []byte(`<title>{{block "title" .}}App{{end}}</title>{{template "content" .}}`)},
+			"views/partials/nav.html":  &fstest.MapFile{Data: []byte(`{{define "nav"}}<nav>{{upper .}}</nav>{{end}}`)},
+			"views/users/show.html":    &fstest.MapFile{Data: []byte(`{{define "title"}}User{{end}}{{define "content"}}{{template "nav" .}}<p>{{.}}</p>{{end}}`)},
+			"views/index.html":         &fstest.MapFile{Data: []byte(`{{define "content"}}<p>{{.}}</p>{{end}}`)},
+			"views/users/README.md":    &fstest.MapFile{Data: []byte(`{{.}}`)},
+			"views/emails/welcome.txt": &fstest.MapFile{Data: []byte(`Hello {{.}}`)},
+		}
+
+		config = &template.Config{
+			Dir:    "views",
+			Layout: "layouts/base.html",
+			Funcs: template.FuncMap{
+				"upper": strings.ToUpper,
+			},
+		}
+	})
+
+	It("parses the pages", func() {
+		set, err := template.New(parcello.FromFS(fileSystem), config)
+		Expect(err).To(BeNil())
+		Expect(set.Names()).To(Equal([]string{"index.html", "users/show.html"}))
+	})
+
+	It("renders the pages with the layout", func() {
+		set, err := template.New(parcello.FromFS(fileSystem), config)
+		Expect(err).To(BeNil())
+
+		Expect(render(set, "users/show.html", "<jack>")).To(Equal("<title>User</title><nav>&lt;JACK&gt;</nav><p>&lt;jack&gt;</p>"))
+		Expect(render(set, "/index.html", "home")).To(Equal("<title>App</title><p>home</p>"))
+	})
+
+	It("renders the pages from a bundle", func() {
+		compressor := &parcello.ZipCompressor{
+			Config: &parcello.CompressorConfig{
+				Logger:   ioutil.Discard,
+				Filename: "bundle",
+				Recurive: true,
+			},
+		}
+
+		bundle, err := compressor.Compress(&parcello.CompressorContext{
+			FileSystem: parcello.FromFS(fileSystem),
+		})
+		Expect(err).To(BeNil())
+
+		manager := &parcello.ResourceManager{}
+		Expect(manager.Add(parcello.BinaryResource(bundle.Body))).To(Succeed())
+
+		set, err := template.New(manager, config)
+		Expect(err).To(BeNil())
+		Expect(render(set, "index.html", "home")).To(Equal("<title>App</title><p>home</p>"))
+	})
+
+	Context("when the layout is not configured", func() {
+		It("executes the pages", func() {
+			fileSystem["views/about.html"] = &fstest.MapFile{Data: []byte(`<h1>{{.}}</h1>`)}
+			config.Layout = ""
+
+			set, err := template.New(parcello.FromFS(fileSystem), config)
+			Expect(err).To(BeNil())
+			Expect(render(set, "about.html", "About")).To(Equal("<h1>About</h1>"))
+		})
+	})
+
+	Context("when the templates are text", func() {
+		It("does not escape the output", func() {
+			config.Text = true
+			config.Layout = ""
+			config.Extensions = []string{".txt"}
+
+			set, err := template.New(parcello.FromFS(fileSystem), config)
+			Expect(err).To(BeNil())
+			Expect(set.Names()).To(Equal([]string{"emails/welcome.txt"}))
+			Expect(render(set, "emails/welcome.txt", "<jack>")).To(Equal("Hello <jack>"))
+		})
+	})
+
+	Context("when the template does not exist", func() {
+		It("returns an error", func() {
+			set, err := template.New(parcello.FromFS(fileSystem), config)
+			Expect(err).To(BeNil())
+
+			err = set.Execute(ioutil.Discard, "users/edit.html", nil)
+			Expect(err).To(MatchError("The template 'users/edit.html' does not exist"))
+		})
+	})
+
+	Context("when the template is invalid", func() {
+		It("returns an error", func() {
+			fileSystem["views/partials/nav.html"].Data = []byte(`{{define "nav"}}`)
+
+			set, err := template.New(parcello.FromFS(fileSystem), config)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("partials/nav.html"))
+			Expect(set).To(BeNil())
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		It("returns an error", func() {
+			config.Dir = "templates"
+
+			set, err := template.New(&parcello.ResourceManager{}, config)
+			Expect(err).To(HaveOccurred())
+			Expect(set).To(BeNil())
+		})
+	})
+
+	Context("when the templates are loaded from a directory", func() {
+		var dir string
+
+		write := func(name, content string) {
+			path := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+
+			dir, err = ioutil.TempDir("", "parcello")
+			Expect(err).To(BeNil())
+
+			parcello.PollInterval = 10 * time.Millisecond
+
+			write("views/layouts/base.html", `[{{template "content" .}}]`)
+			write("views/index.html", `{{define "content"}}{{.}}{{end}}`)
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("re-parses the templates when they change", func() {
+			set, err := template.New(parcello.Dir(dir), config)
+			Expect(err).To(BeNil())
+			defer set.Close()
+
+			Expect(render(set, "index.html", "home")).To(Equal("[home]"))
+
+			write("views/layouts/base.html", `({{template "content" .}})`)
+
+			Eventually(func() string {
+				return render(set, "index.html", "home")
+			}, 5*time.Second, 10*time.Millisecond).Should(Equal("(home)"))
+
+			write("views/about.html", `{{define "content"}}about{{end}}`)
+
+			Eventually(set.Names, 5*time.Second, 10*time.Millisecond).Should(ContainElement("about.html"))
+		})
+
+		It("re-parses the templates when their directory is renamed", func() {
+			write("views/users/show.html", `{{define "content"}}user{{end}}`)
+
+			set, err := template.New(parcello.Dir(dir), config)
+			Expect(err).To(BeNil())
+			defer set.Close()
+
+			Expect(set.Names()).To(ContainElement("users/show.html"))
+
+			Expect(os.Rename(filepath.Join(dir, "views", "users"), filepath.Join(dir, "users"))).To(Succeed())
+
+			Eventually(set.Names, 5*time.Second, 10*time.Millisecond).ShouldNot(ContainElement("users/show.html"))
+		})
+	})
+})
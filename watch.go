@@ -0,0 +1,367 @@
+package parcello
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// PollInterval is the interval of the polling watchers
+var PollInterval = time.Second
+
+// Op describes a set of changes
+type Op uint32
+
+const (
+	// Create is reported when a resource is created
+	Create Op = 1 << iota
+	// Write is reported when the content of a resource is changed
+	Write
+	// Remove is reported when a resource is removed
+	Remove
+	// Rename is reported when a resource is renamed
+	Rename
+)
+
+// String returns the names of the changes
+func (op Op) String() string {
+	names := []string{}
+
+	for _, item := range []struct {
+		op   Op
+		name string
+	}{
+		{Create, "CREATE"},
+		{Write, "WRITE"},
+		{Remove, "REMOVE"},
+		{Rename, "RENAME"},
+	} {
+		if op&item.op == item.op {
+			names = append(names, item.name)
+		}
+	}
+
+	return strings.Join(names, "|")
+}
+
+// Event describes a change of a resource
+type Event struct {
+	// Name is the path of the resource
+	Name string
+	// Op is the change of the resource
+	Op Op
+	// Err is the error that occurred while watching the resources. The Name
+	// and the Op are empty if it is set.
+	Err error
+}
+
+// Poll watches the resources of the file system that match the pattern by
+// walking the file system periodically. The changes are delivered until the
+// context is done. The errors of the walks are delivered as events as well.
+func Poll(ctx context.Context, fileSystem FileSystem, pattern string, interval time.Duration) (<-chan Event, error) {
+	state, err := snapshot(fileSystem, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := snapshot(fileSystem, pattern)
+			if err != nil {
+				if !send(ctx, events, Event{Err: err}) {
+					return
+				}
+
+				continue
+			}
+
+			for _, event := range changes(state, next) {
+				if !send(ctx, events, event) {
+					return
+				}
+			}
+
+			state = next
+		}
+	}()
+
+	return events, nil
+}
+
+// Watch watches the resources that match the pattern and delivers their
+// changes until the context is done. It uses the file system notifications
+// and falls back to polling if they are not available.
+func (d Dir) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	if _, err := match(pattern, "", ""); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(string(d)); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return Poll(ctx, d, pattern, PollInterval)
+	}
+
+	if err := d.watch(watcher, string(d)); err != nil {
+		watcher.Close()
+		return Poll(ctx, d, pattern, PollInterval)
+	}
+
+	events := make(chan Event)
+	go d.notify(ctx, watcher, pattern, events)
+
+	return events, nil
+}
+
+// watch adds the directory and its sub-directories to the watcher
+func (d Dir) watch(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+// notify delivers the file system notifications that match the pattern
+func (d Dir) notify(ctx context.Context, watcher *fsnotify.Watcher, pattern string, events chan<- Event) {
+	defer close(events)
+	defer watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			if !send(ctx, events, Event{Err: err}) {
+				return
+			}
+		case notification, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if notification.Op&fsnotify.Create == fsnotify.Create {
+				if info, err := os.Stat(notification.Name); err == nil && info.IsDir() {
+					_ = d.watch(watcher, notification.Name)
+				}
+			}
+
+			op := eventOp(notification.Op)
+			if op == 0 {
+				continue
+			}
+
+			name, err := filepath.Rel(string(d), notification.Name)
+			if err != nil {
+				continue
+			}
+
+			event := Event{Name: filepath.ToSlash(name), Op: op}
+
+			if !matches(pattern, event.Name) {
+				continue
+			}
+
+			if !send(ctx, events, event) {
+				return
+			}
+		}
+	}
+}
+
+// eventOp returns the changes of the file system notification. The changes of
+// the permissions are not reported.
+func eventOp(op fsnotify.Op) Op {
+	result := Op(0)
+
+	for _, item := range []struct {
+		from fsnotify.Op
+		to   Op
+	}{
+		{fsnotify.Create, Create},
+		{fsnotify.Write, Write},
+		{fsnotify.Remove, Remove},
+		{fsnotify.Rename, Rename},
+	} {
+		if op&item.from == item.from {
+			result |= item.to
+		}
+	}
+
+	return result
+}
+
+// Watch does not deliver any changes, because the bundled resources do not
+// change. The channel is closed when the context is done.
+func (m *ResourceManager) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	return idle(ctx, pattern)
+}
+
+// Watch does not deliver any changes, because fs.FS is read-only. The
+// channel is closed when the context is done.
+func (f *FS) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	return idle(ctx, pattern)
+}
+
+// Watch delivers the changes of all layers
+func (o *Overlay) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		group  sync.WaitGroup
+		events = make(chan Event)
+	)
+
+	for _, layer := range o.Layers {
+		changes, err := layer.Watch(ctx, pattern)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		group.Add(1)
+
+		go func(changes <-chan Event) {
+			defer group.Done()
+
+			for event := range changes {
+				if !send(ctx, events, event) {
+					return
+				}
+			}
+		}(changes)
+	}
+
+	go func() {
+		group.Wait()
+		cancel()
+		close(events)
+	}()
+
+	return events, nil
+}
+
+// idle returns a channel that is closed when the context is done
+func idle(ctx context.Context, pattern string) (<-chan Event, error) {
+	if _, err := match(pattern, "", ""); err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+
+	return events, nil
+}
+
+// stamp identifies the version of a resource
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot returns the stamps of the resources that match the pattern
+func snapshot(fileSystem FileSystem, pattern string) (map[string]stamp, error) {
+	if _, err := match(pattern, "", ""); err != nil {
+		return nil, err
+	}
+
+	state := map[string]stamp{}
+
+	err := fileSystem.Walk("/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(filepath.ToSlash(path), "/")
+
+		if info.IsDir() || !matches(pattern, name) {
+			return nil
+		}
+
+		state[name] = stamp{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+
+	return state, err
+}
+
+// changes returns the changes between two snapshots sorted by name
+func changes(prev, next map[string]stamp) []Event {
+	events := []Event{}
+
+	for name, current := range next {
+		previous, ok := prev[name]
+
+		switch {
+		case !ok:
+			events = append(events, Event{Name: name, Op: Create})
+		case previous != current:
+			events = append(events, Event{Name: name, Op: Write})
+		}
+	}
+
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			events = append(events, Event{Name: name, Op: Remove})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
+
+	return events
+}
+
+// matches returns true if the pattern is empty or it matches the path or the
+// name of the resource
+func matches(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	matched, _ := match(pattern, name, filepath.Base(name))
+	return matched
+}
+
+// send delivers the event unless the context is done
+func send(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
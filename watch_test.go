@@ -0,0 +1,195 @@
+package parcello_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/phogolabs/parcello"
+)
+
+var _ = Describe("Watch", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		dir    string
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "parcello")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "templates"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "templates", "index.html"), []byte("index"), 0600)).To(Succeed())
+
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+	}
+
+	Describe("Dir", func() {
+		It("delivers the changes of the matching resources", func() {
+			events, err := parcello.Dir(dir).Watch(ctx, "*.html")
+			Expect(err).NotTo(HaveOccurred())
+
+			write("templates/index.txt", "ignored")
+			write("templates/index.html", "changed")
+
+			Eventually(events).Should(Receive(Equal(parcello.Event{
+				Name: "templates/index.html",
+				Op:   parcello.Write,
+			})))
+		})
+
+		It("watches the new directories", func() {
+			events, err := parcello.Dir(dir).Watch(ctx, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(dir, "partials"), 0700)).To(Succeed())
+			Eventually(events).Should(Receive(Equal(parcello.Event{Name: "partials", Op: parcello.Create})))
+
+			// give the watcher a moment to watch the new directory
+			time.Sleep(50 * time.Millisecond)
+
+			write("partials/header.html", "header")
+			Eventually(events).Should(Receive(Equal(parcello.Event{Name: "partials/header.html", Op: parcello.Create})))
+		})
+
+		It("closes the channel when the context is done", func() {
+			events, err := parcello.Dir(dir).Watch(ctx, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			cancel()
+			Eventually(events).Should(BeClosed())
+		})
+
+		Context("when the pattern is invalid", func() {
+			It("returns an error", func() {
+				events, err := parcello.Dir(dir).Watch(ctx, "[")
+				Expect(events).To(BeNil())
+				Expect(err).To(Equal(filepath.ErrBadPattern))
+			})
+		})
+
+		Context("when the directory does not exist", func() {
+			It("returns an error", func() {
+				events, err := parcello.Dir(filepath.Join(dir, "unknown")).Watch(ctx, "")
+				Expect(events).To(BeNil())
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Poll", func() {
+		It("delivers the changes of the matching resources", func() {
+			events, err := parcello.Poll(ctx, parcello.Dir(dir), "*.html", 10*time.Millisecond)
+			Expect(err).NotTo(HaveOccurred())
+
+			write("templates/header.html", "header")
+			Eventually(events).Should(Receive(Equal(parcello.Event{Name: "templates/header.html", Op: parcello.Create})))
+
+			write("templates/header.html", "header changed")
+			Eventually(events).Should(Receive(Equal(parcello.Event{Name: "templates/header.html", Op: parcello.Write})))
+
+			Expect(os.Remove(filepath.Join(dir, "templates", "header.html"))).To(Succeed())
+			Eventually(events).Should(Receive(Equal(parcello.Event{Name: "templates/header.html", Op: parcello.Remove})))
+
+			write("templates/index.txt", "ignored")
+			Consistently(events, 100*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("closes the channel when the context is done", func() {
+			events, err := parcello.Poll(ctx, parcello.Dir(dir), "", 10*time.Millisecond)
+			Expect(err).NotTo(HaveOccurred())
+
+			cancel()
+			Eventually(events).Should(BeClosed())
+		})
+
+		Context("when the file system cannot be walked anymore", func() {
+			It("delivers the error", func() {
+				root := filepath.Join(dir, "templates")
+				Expect(os.MkdirAll(root, 0700)).To(Succeed())
+
+				events, err := parcello.Poll(ctx, parcello.Dir(root), "", 10*time.Millisecond)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.RemoveAll(root)).To(Succeed())
+
+				var event parcello.Event
+				Eventually(events).Should(Receive(&event))
+				Expect(event.Name).To(BeEmpty())
+				Expect(os.IsNotExist(event.Err)).To(BeTrue())
+			})
+		})
+
+		Context("when the file system cannot be walked", func() {
+			It("returns an error", func() {
+				events, err := parcello.Poll(ctx, parcello.Dir(filepath.Join(dir, "unknown")), "", time.Millisecond)
+				Expect(events).To(BeNil())
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("ResourceManager", func() {
+		It("does not deliver any changes", func() {
+			manager := &parcello.ResourceManager{}
+
+			events, err := manager.Watch(ctx, "*.html")
+			Expect(err).NotTo(HaveOccurred())
+			Consistently(events, 50*time.Millisecond).ShouldNot(Receive())
+
+			cancel()
+			Eventually(events).Should(BeClosed())
+		})
+	})
+
+	Describe("FS", func() {
+		It("does not deliver any changes", func() {
+			events, err := parcello.FromFS(fstest.MapFS{}).Watch(ctx, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			cancel()
+			Eventually(events).Should(BeClosed())
+		})
+	})
+
+	Describe("Overlay", func() {
+		It("delivers the changes of all layers", func() {
+			overlay := parcello.NewOverlay(parcello.Dir(dir), &parcello.ResourceManager{})
+
+			events, err := overlay.Watch(ctx, "*.html")
+			Expect(err).NotTo(HaveOccurred())
+
+			write("templates/index.html", "changed")
+			Eventually(events).Should(Receive(Equal(parcello.Event{Name: "templates/index.html", Op: parcello.Write})))
+
+			cancel()
+			Eventually(events).Should(BeClosed())
+		})
+	})
+
+	Describe("Op", func() {
+		It("returns the names of the changes", func() {
+			Expect((parcello.Create | parcello.Write).String()).To(Equal("CREATE|WRITE"))
+			Expect(parcello.Remove.String()).To(Equal("REMOVE"))
+		})
+	})
+})